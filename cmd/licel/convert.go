@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+)
+
+// runConvert — преобразует входы в формат, определяемый выходом (-o)
+func runConvert(args []string) error {
+	fs, cfg := newFlagSet("convert", "[flags] inputs...")
+	var output string
+	var level int
+	cfg.addOutputFlags(fs, &output, &level)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	pack, err := cfg.loadInputs(inputs)
+	if err != nil {
+		return err
+	}
+	return cfg.writeOutput(pack, output, level)
+}
+
+// runMerge — объединяет несколько входов в один выход.
+// Совпадающие имена файлов считаются ошибкой, если не задан -overwrite.
+func runMerge(args []string) error {
+	fs, cfg := newFlagSet("merge", "[flags] inputs...")
+	var output string
+	var level int
+	var overwrite bool
+	cfg.addOutputFlags(fs, &output, &level)
+	fs.BoolVar(&overwrite, "overwrite", false, "let later inputs replace files with the same name")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	merged, err := cfg.loadInput(inputs[0])
+	if err != nil {
+		return err
+	}
+	for _, input := range inputs[1:] {
+		pack, err := cfg.loadInput(input)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			for name := range pack.Data {
+				if _, ok := merged.Data[name]; ok {
+					return fmt.Errorf("%s: file %q already present; use -overwrite to replace it", input, name)
+				}
+			}
+		}
+		merged.Merge(pack)
+	}
+	return cfg.writeOutput(merged, output, level)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+)
+
+// timeFlagLayouts — допустимые форматы времени в -from/-to
+var timeFlagLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", timeLayout, "2006-01-02"}
+
+// parseTimeFlag — разбирает значение флага времени в поясе loc; пустая строка означает «без ограничения»
+func parseTimeFlag(name, value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range timeFlagLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, usagef("-%s: cannot parse time %q", name, value)
+}
+
+// runFilter — оставляет файлы и профили, удовлетворяющие условиям
+func runFilter(args []string) error {
+	fs, cfg := newFlagSet("filter", "[flags] inputs...")
+	var output string
+	var level int
+	var wvl float64
+	var pol, kind, site, from, to string
+	cfg.addOutputFlags(fs, &output, &level)
+	fs.Float64Var(&wvl, "wl", 0, "keep profiles with this wavelength, nm (0 = any)")
+	fs.StringVar(&pol, "pol", "", "keep profiles with this polarization (empty = any)")
+	fs.StringVar(&kind, "type", "", "keep profiles of this type: analog, photon or glued (empty = any)")
+	fs.StringVar(&site, "site", "", "keep files from this measurement site (empty = any)")
+	fs.StringVar(&from, "from", "", "keep files starting at or after this time")
+	fs.StringVar(&to, "to", "", "keep files starting before this time")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+
+	var isKind func(pr *licelformat.LicelProfile) bool
+	switch kind {
+	case "":
+	case "analog":
+		isKind = (*licelformat.LicelProfile).IsAnalog
+	case "photon":
+		isKind = (*licelformat.LicelProfile).IsPhoton
+	case "glued":
+		isKind = (*licelformat.LicelProfile).IsGlued
+	default:
+		return usagef("-type must be analog, photon or glued, got %q", kind)
+	}
+	fromTime, err := parseTimeFlag("from", from, cfg.location)
+	if err != nil {
+		return err
+	}
+	toTime, err := parseTimeFlag("to", to, cfg.location)
+	if err != nil {
+		return err
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	pack, err := cfg.loadInputs(inputs)
+	if err != nil {
+		return err
+	}
+
+	files := pack.Filter(func(lf *licelformat.LicelFile) bool {
+		if site != "" && lf.MeasurementSite != site {
+			return false
+		}
+		if !fromTime.IsZero() && lf.MeasurementStartTime.Before(fromTime) {
+			return false
+		}
+		if !toTime.IsZero() && !lf.MeasurementStartTime.Before(toTime) {
+			return false
+		}
+		return true
+	})
+	result := files.FilterProfiles(func(pr *licelformat.LicelProfile) bool {
+		if wvl != 0 && pr.Wavelength != wvl {
+			return false
+		}
+		if pol != "" && pr.Polarization != pol {
+			return false
+		}
+		if isKind != nil && !isKind(pr) {
+			return false
+		}
+		return true
+	})
+	if len(result.Data) == 0 {
+		return fmt.Errorf("no files match the filter")
+	}
+	return cfg.writeOutput(&result, output, level)
+}
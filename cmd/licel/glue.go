@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+)
+
+// glueBlends — значения флага -blend
+var glueBlends = map[string]licelformat.GlueBlend{
+	"mean":    licelformat.BlendMean,
+	"linear":  licelformat.BlendLinear,
+	"cosine":  licelformat.BlendCosine,
+	"sigmoid": licelformat.BlendSigmoid,
+	"snr":     licelformat.BlendSNR,
+}
+
+// runGlue — склеивает аналоговый и фотонный каналы во всех файлах входов
+func runGlue(args []string) error {
+	fs, cfg := newFlagSet("glue", "-wl nm {-h1 m -h2 m | -auto} [flags] inputs...")
+	var output string
+	var level int
+	var wvl, h1, h2 float64
+	var pol, blend string
+	var align, paralyzable, auto, regression, verbose bool
+	var deadTime float64
+	cfg.addOutputFlags(fs, &output, &level)
+	fs.Float64Var(&wvl, "wl", 0, "wavelength, nm (required)")
+	fs.Float64Var(&h1, "h1", 0, "lower bound of the gluing range, m; with -auto the lower bound of the search")
+	fs.Float64Var(&h2, "h2", 0, "upper bound of the gluing range, m (required without -auto); with -auto the upper bound of the search, 0 = profile end")
+	fs.BoolVar(&auto, "auto", false, "choose the gluing range automatically where the photon channel is linear and the analog one is above noise")
+	fs.BoolVar(&regression, "regression", false, "fit analog = k*photon + offset by least squares instead of the mean ratio")
+	fs.StringVar(&blend, "blend", "mean", "blending of the channels within the gluing range: mean, linear, cosine, sigmoid or snr")
+	fs.BoolVar(&verbose, "v", false, "print gluing coefficients and fit quality of every file to stderr")
+	fs.StringVar(&pol, "pol", "", "polarization (empty matches any)")
+	fs.Float64Var(&deadTime, "dead-time", 0, "dead time of the photon channel, ns (0 = no correction)")
+	fs.BoolVar(&paralyzable, "paralyzable", false, "use the paralyzable dead-time model instead of non-paralyzable")
+	fs.BoolVar(&align, "align", false, "shift channels by their header bin shifts so that range zero is bin 0 before gluing")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	if wvl <= 0 {
+		return usagef("-wl is required")
+	}
+	b, ok := glueBlends[blend]
+	if !ok {
+		return usagef("-blend must be mean, linear, cosine, sigmoid or snr, got %q", blend)
+	}
+	if deadTime < 0 {
+		return usagef("-dead-time must not be negative, got %g", deadTime)
+	}
+	if !auto && h2 <= h1 {
+		return usagef("-h2 (%g) must be greater than -h1 (%g)", h2, h1)
+	}
+	if auto && h2 != 0 && h2 <= h1 {
+		return usagef("-h2 (%g) must be 0 or greater than -h1 (%g)", h2, h1)
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	pack, err := cfg.loadInputs(inputs)
+	if err != nil {
+		return err
+	}
+	if align {
+		if err := pack.AlignZeroBins(); err != nil {
+			return err
+		}
+	}
+	if deadTime > 0 {
+		model := licelformat.DeadTimeNonParalyzable
+		if paralyzable {
+			model = licelformat.DeadTimeParalyzable
+		}
+		dt := licelformat.DeadTime{Channel: licelformat.Channel{Wavelength: wvl, Polarization: pol}, Tau: deadTime, Model: model}
+		if err := pack.CorrectDeadTime(dt); err != nil {
+			return err
+		}
+	}
+	opts := licelformat.GlueOptions{Blend: b, From: h1, To: h2, AutoWindow: auto}
+	if regression {
+		opts.Method = licelformat.GlueRegression
+	}
+	results, err := pack.GlueWith(wvl, pol, opts)
+	if err != nil {
+		return err
+	}
+	if verbose {
+		for _, name := range pack.Names() {
+			r := results[name]
+			fmt.Fprintf(stderr, "licel: %s: glue %s blend %s k=%g offset=%g range [%g, %g] m bins [%d, %d] used %d r2=%.4f residual=%g\n",
+				name, r.Method, r.Blend, r.K, r.Offset, r.From, r.To, r.First, r.Last, r.Bins, r.R2, r.Residual)
+		}
+	}
+	return cfg.writeOutput(pack, output, level)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+)
+
+// timeLayout — формат времени в выводе команд
+const timeLayout = "2006-01-02 15:04:05"
+
+// fileInfo — запись JSON-вывода команды info: имя файла и поля LicelFile с их json-тегами
+type fileInfo struct {
+	File string `json:"file"`
+	licelformat.LicelFile
+}
+
+// runInfo — печатает заголовки файлов и таблицы их профилей
+func runInfo(args []string) error {
+	fs, cfg := newFlagSet("info", "[flags] inputs...")
+	var asJSON, withData, short bool
+	fs.BoolVar(&asJSON, "json", false, "print JSON using the library struct tags")
+	fs.BoolVar(&withData, "data", false, "include profile data in JSON output")
+	fs.BoolVar(&short, "short", false, "print one summary line per file")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if withData && !asJSON {
+		return usagef("-data requires -json")
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	// Без -data бинарные данные не нужны
+	var opts []licelformat.LoadOption
+	if !withData {
+		opts = append(opts, licelformat.WithHeaderOnly())
+	}
+	pack, err := cfg.loadInputs(inputs, opts...)
+	if err != nil {
+		return err
+	}
+	names := pack.Names()
+
+	switch {
+	case asJSON:
+		infos := make([]fileInfo, 0, len(names))
+		for _, name := range names {
+			infos = append(infos, fileInfo{File: name, LicelFile: pack.Data[name]})
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	case short:
+		for _, name := range names {
+			lf := pack.Data[name]
+			fmt.Fprintf(stdout, "%s\t%s\t%s\t%s\t%d profiles\n",
+				name, lf.MeasurementSite,
+				lf.MeasurementStartTime.Format(timeLayout),
+				lf.MeasurementStopTime.Format(timeLayout),
+				lf.NDatasets)
+		}
+		return nil
+	}
+
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(stdout)
+		}
+		lf := pack.Data[name]
+		if err := printFileInfo(stdout, name, &lf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printFileInfo — человекочитаемый вывод заголовка файла и таблицы профилей
+func printFileInfo(w io.Writer, name string, lf *licelformat.LicelFile) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(tw, "File:\t%s\n", name)
+	if lf.FileName != "" && lf.FileName != filepath.Base(name) {
+		fmt.Fprintf(tw, "Header name:\t%s\n", lf.FileName)
+	}
+	fmt.Fprintf(tw, "Variant:\t%s\n", lf.Variant)
+	fmt.Fprintf(tw, "Site:\t%s\n", lf.MeasurementSite)
+	fmt.Fprintf(tw, "Start:\t%s\n", lf.MeasurementStartTime.Format(timeLayout))
+	fmt.Fprintf(tw, "Stop:\t%s\n", lf.MeasurementStopTime.Format(timeLayout))
+	fmt.Fprintf(tw, "Altitude:\t%g m\n", lf.AltitudeAboveSeaLevel)
+	fmt.Fprintf(tw, "Latitude:\t%g\n", lf.Latitude)
+	fmt.Fprintf(tw, "Longitude:\t%g\n", lf.Longitude)
+	fmt.Fprintf(tw, "Zenith:\t%g\n", lf.Zenith)
+	if len(lf.HeaderExtra) > 0 {
+		fmt.Fprintf(tw, "Extra fields:\t%s\n", strings.Join(lf.HeaderExtra, " "))
+	}
+	fmt.Fprintf(tw, "Laser 1:\t%d shots, %d Hz\n", lf.Laser1NShots, lf.Laser1Freq)
+	fmt.Fprintf(tw, "Laser 2:\t%d shots, %d Hz\n", lf.Laser2NShots, lf.Laser2Freq)
+	fmt.Fprintf(tw, "Laser 3:\t%d shots, %d Hz\n", lf.Laser3NShots, lf.Laser3Freq)
+	if lf.Variant == licelformat.VariantLaser4 {
+		fmt.Fprintf(tw, "Laser 4:\t%d shots, %d Hz\n", lf.Laser4NShots, lf.Laser4Freq)
+	}
+	if len(lf.LaserExtra) > 0 {
+		fmt.Fprintf(tw, "Laser extra:\t%s\n", strings.Join(lf.LaserExtra, " "))
+	}
+	fmt.Fprintf(tw, "Datasets:\t%d\n", lf.NDatasets)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "#\tWL, nm\tPOL\tDEVICE\tBIN, m\tPOINTS\tSHOTS\tHV, V\tADC\tDISCR\tUNITS\tACTIVE\tEXTRA\t")
+	for i, pr := range lf.Profiles {
+		fmt.Fprintf(tw, "%d\t%g\t%s\t%s%d\t%.2f\t%d\t%d\t%d\t%d\t%g\t%s\t%t\t%s\t\n",
+			i, pr.Wavelength, pr.Polarization, pr.DeviceID, pr.NCrate,
+			pr.BinWidth, pr.NDataPoints, pr.NShots, pr.HighVoltage,
+			pr.AdcBits, pr.DiscrLevel, pr.Units(), pr.Active, strings.Join(pr.Extra, " "))
+	}
+	return tw.Flush()
+}
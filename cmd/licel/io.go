@@ -0,0 +1,182 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+)
+
+// stdinName — имя, под которым в паке хранится файл, прочитанный со stdin
+const stdinName = "stdin"
+
+// config — общие параметры загрузки и записи одного запуска подкоманды
+type config struct {
+	location *time.Location // часовой пояс времён в заголовках LICEL-файлов (-tz)
+	lenient  bool           // нестрогий разбор повреждённых файлов (-lenient)
+	clamp    bool           // записывать непредставимые отсчёты границей диапазона (-clamp)
+	negative bool           // разрешить отрицательные отсчёты (-negative)
+}
+
+// newConfig — параметры по умолчанию: времена в UTC, строгий разбор и запись
+func newConfig() *config {
+	return &config{location: time.UTC}
+}
+
+// addLoadFlags — флаги -tz (часовой пояс для чтения и записи времён заголовка и для -from/-to)
+// и -lenient (восстановление частично повреждённых файлов)
+func (c *config) addLoadFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.lenient, "lenient", false, "load partially corrupted files, printing warnings to stderr")
+	fs.Func("tz", "time zone of LICEL header times: UTC, Local or an IANA name (default UTC)", func(s string) error {
+		loc, err := time.LoadLocation(s)
+		if err != nil {
+			return fmt.Errorf("unknown time zone %q", s)
+		}
+		c.location = loc
+		return nil
+	})
+}
+
+// loadOptions — опции загрузки из флагов -tz и -lenient, дополненные opts
+func (c *config) loadOptions(opts ...licelformat.LoadOption) []licelformat.LoadOption {
+	res := []licelformat.LoadOption{licelformat.WithLocation(c.location)}
+	if c.lenient {
+		res = append(res, licelformat.WithLenient())
+	}
+	return append(res, opts...)
+}
+
+// loadInput — загружает один вход и выводит предупреждения нестрогого разбора
+func (c *config) loadInput(input string, opts ...licelformat.LoadOption) (*licelformat.LicelPack, error) {
+	pack, err := readInput(input, c.loadOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range pack.Names() {
+		printWarnings(name, pack.Data[name].Warnings)
+	}
+	return pack, nil
+}
+
+// readInput — загружает один вход: "-", *.zip, *.nc или glob-маску
+func readInput(input string, opts []licelformat.LoadOption) (*licelformat.LicelPack, error) {
+	switch {
+	case input == "-":
+		lf, err := licelformat.LoadLicelFileFromReader(stdin, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+		pack := &licelformat.LicelPack{}
+		pack.Merge(&licelformat.LicelPack{Data: map[string]licelformat.LicelFile{stdinName: lf}})
+		return pack, nil
+	case hasSuffixFold(input, ".zip"):
+		return licelformat.NewLicelPackFromZip(input, opts...)
+	case hasSuffixFold(input, ".nc"):
+		return licelformat.LoadLicelPackFromNetCDF3(input, opts...)
+	}
+
+	pack, err := licelformat.NewLicelPack(input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(pack.Data) == 0 {
+		return nil, fmt.Errorf("no files match %q", input)
+	}
+	return pack, nil
+}
+
+// loadInputs — загружает все входы и объединяет их в один пак
+func (c *config) loadInputs(inputs []string, opts ...licelformat.LoadOption) (*licelformat.LicelPack, error) {
+	pack := &licelformat.LicelPack{Data: make(map[string]licelformat.LicelFile)}
+	for _, input := range inputs {
+		p, err := c.loadInput(input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		pack.Merge(p)
+	}
+	return pack, nil
+}
+
+// printWarnings — выводит в stderr предупреждения нестрогого разбора файла name
+func printWarnings(name string, warnings []licelformat.ParseWarning) {
+	for _, w := range warnings {
+		fmt.Fprintf(stderr, "licel: %s: warning: %s\n", name, w)
+	}
+}
+
+// writeOutput — сохраняет пак в output:
+//   - "-": единственный файл пака в формате LICEL на stdout;
+//   - *.nc: NetCDF3;
+//   - *.zip: zip-архив с уровнем сжатия level;
+//   - иначе: каталог, в который файлы сохраняются под своими базовыми именами.
+func (c *config) writeOutput(pack *licelformat.LicelPack, output string, level int) error {
+	if len(pack.Data) == 0 {
+		return fmt.Errorf("nothing to write: no files left")
+	}
+
+	switch {
+	case output == "-":
+		if len(pack.Data) != 1 {
+			return fmt.Errorf("stdout can hold a single LICEL file, got %d; use a .zip, .nc or directory output", len(pack.Data))
+		}
+		for name, lf := range pack.Data {
+			// файл со stdin сохраняет имя из своего заголовка
+			fname := filepath.Base(name)
+			if name == stdinName {
+				fname = ""
+			}
+			return lf.WriteTo(stdout, fname, c.writeOptions(name)...)
+		}
+	case hasSuffixFold(output, ".nc"):
+		return pack.SaveToNetCDF3(output)
+	case hasSuffixFold(output, ".zip"):
+		pack.ZipCompressionLevel = level
+		return pack.SaveToZip(output, c.writeOptions(output)...)
+	}
+
+	if err := os.MkdirAll(output, 0o755); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	for _, name := range pack.Names() {
+		lf := pack.Data[name]
+		if err := lf.Save(filepath.Join(output, filepath.Base(name)), c.writeOptions(name)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeOptions — опции записи из флагов -tz, -clamp и -negative; о приведённых отсчётах
+// файла name сообщается в stderr
+func (c *config) writeOptions(name string) []licelformat.WriteOption {
+	opts := []licelformat.WriteOption{licelformat.WithWriteLocation(c.location)}
+	if c.negative {
+		opts = append(opts, licelformat.WithNegativeCounts())
+	}
+	if c.clamp {
+		opts = append(opts, licelformat.WithClamp(func(r licelformat.ClampReport) {
+			fmt.Fprintf(stderr, "licel: %s: warning: %s\n", name, r)
+		}))
+	}
+	return opts
+}
+
+// addOutputFlags — общие флаги вывода для команд, изменяющих данные
+func (c *config) addOutputFlags(fs *flag.FlagSet, output *string, level *int) {
+	fs.StringVar(output, "o", "-", "output: \"-\" (stdout, single file), *.zip, *.nc or a directory")
+	fs.IntVar(level, "level", 0, "zip deflate level 1-9 (0 = default)")
+	fs.BoolVar(&c.clamp, "clamp", false, "clamp samples that do not fit LICEL counts instead of failing, printing warnings to stderr")
+	fs.BoolVar(&c.negative, "negative", false, "write negative counts (e.g. after background subtraction) instead of failing")
+}
+
+// checkLevel — проверяет уровень сжатия zip
+func checkLevel(level int) error {
+	if level < 0 || level > 9 {
+		return usagef("-level must be in [0, 9], got %d", level)
+	}
+	return nil
+}
@@ -1,27 +1,169 @@
+// Команда licel — утилита командной строки для работы с LICEL-файлами.
+//
+// Использование:
+//
+//	licel <command> [flags] [inputs...]
+//
+// Входные данные: путь к файлу, glob-маска, zip-архив (*.zip), NetCDF3 (*.nc)
+// или "-" для чтения одного LICEL-файла со stdin.
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+)
 
-	"github.com/physicist2018/licelfile/v2/licelformat"
+// Коды завершения
+const (
+	exitOK      = 0 // успешное выполнение
+	exitFailure = 1 // ошибка выполнения или невалидные данные
+	exitUsage   = 2 // неверные аргументы командной строки
+)
+
+// command — описание подкоманды
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"info", "print file headers and profile tables", runInfo},
+	{"convert", "convert between LICEL, zip and NetCDF3", runConvert},
+	{"glue", "glue analog and photon channels", runGlue},
+	{"trim", "cut profiles at a maximum range", runTrim},
+	{"rcs", "save range-corrected signal to NetCDF3", runRCS},
+	{"filter", "keep files and profiles matching conditions", runFilter},
+	{"merge", "merge several inputs into one output", runMerge},
+	{"validate", "check that inputs parse and pass consistency checks", runValidate},
+}
+
+// usageError — ошибка в аргументах командной строки (код завершения 2)
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...any) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
 )
 
 func main() {
-	a, err := licelformat.NewLicelPackFromZip("archive.zip")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
-		os.Exit(1)
+	os.Exit(run(os.Args[1:]))
+}
+
+// run — выбирает подкоманду и переводит ошибку в код завершения
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return exitUsage
 	}
-	err = a.SaveToNetCDF3("1.nc")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error saving file: %v\n", err)
-		os.Exit(1)
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" || name == "-help" {
+		printUsage(stdout)
+		return exitOK
 	}
-	b, err := licelformat.LoadLicelPackFromNetCDF3("1.nc")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error loading file: %v\n", err)
-		os.Exit(1)
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args[1:])
+		switch {
+		case err == nil:
+			return exitOK
+		case errors.Is(err, flag.ErrHelp):
+			return exitOK
+		}
+
+		var uErr *usageError
+		if errors.As(err, &uErr) {
+			fmt.Fprintf(stderr, "licel %s: %v\n", name, err)
+			return exitUsage
+		}
+		var sErr *silentError
+		if errors.As(err, &sErr) {
+			return sErr.code
+		}
+		fmt.Fprintf(stderr, "licel %s: %v\n", name, err)
+		return exitFailure
 	}
-	fmt.Println(b.StartTime)
+
+	fmt.Fprintf(stderr, "licel: unknown command %q\n\n", name)
+	printUsage(stderr)
+	return exitUsage
+}
+
+// silentError — завершение с кодом без дополнительного сообщения (вывод уже сделан командой)
+type silentError struct {
+	code int
+}
+
+func (e *silentError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: licel <command> [flags] [inputs...]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Inputs may be files, glob masks, *.zip archives, *.nc files or \"-\" for stdin.")
+	fmt.Fprintln(w, "Run \"licel <command> -h\" for command flags.")
+}
+
+// newFlagSet — создаёт FlagSet подкоманды с единым форматом справки и её параметры config.
+// Все подкоманды читают LICEL-файлы, поэтому флаги загрузки добавляются здесь.
+func newFlagSet(name, usage string) (*flag.FlagSet, *config) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cfg := newConfig()
+	cfg.addLoadFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: licel %s %s\n\nFlags:\n", name, usage)
+		fs.PrintDefaults()
+	}
+	return fs, cfg
+}
+
+// parseFlags — разбирает флаги, ошибки разбора считаются ошибками использования
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &silentError{code: exitUsage}
+	}
+	return nil
+}
+
+// requireInputs — проверяет, что передан хотя бы один вход
+func requireInputs(fs *flag.FlagSet) ([]string, error) {
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		return nil, usagef("no inputs given")
+	}
+	return inputs, nil
+}
+
+// hasSuffixFold — проверяет расширение без учёта регистра
+func hasSuffixFold(s, suffix string) bool {
+	return strings.HasSuffix(strings.ToLower(s), suffix)
 }
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFile — файл из testdata: 12 профилей, 16380 бинов по 7.5 м
+const testFile = "../../testdata/b2021019.223500"
+
+// runLicel — выполняет run с stdin in и перехваченными stdout/stderr
+func runLicel(t *testing.T, in []byte, args ...string) (code int, out, errOut string) {
+	t.Helper()
+	oldIn, oldOut, oldErr := stdin, stdout, stderr
+	t.Cleanup(func() { stdin, stdout, stderr = oldIn, oldOut, oldErr })
+	var o, e bytes.Buffer
+	stdin, stdout, stderr = bytes.NewReader(in), &o, &e
+	code = run(args)
+	return code, o.String(), e.String()
+}
+
+// copyTestFile — копия testFile в каталоге dir под именем name
+func copyTestFile(t *testing.T, dir, name string) {
+	t.Helper()
+	data, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o644))
+}
+
+// writeNegativeAnalog — копия testFile, в которой аналоговый канал 532 нм (p) отрицателен:
+// склейка с ним даёт значения, непредставимые отсчётами LICEL
+func writeNegativeAnalog(t *testing.T, dir, name string) {
+	t.Helper()
+	lf, err := licelformat.LoadLicelFile(testFile)
+	require.NoError(t, err)
+	for i := range lf.Profiles {
+		pr := &lf.Profiles[i]
+		if pr.IsAnalog() && pr.Wavelength == 532 && pr.Polarization == "p" {
+			for j := range pr.Data {
+				pr.Data[j] = -pr.Data[j] - 1
+			}
+			pr.Raw = nil
+		}
+	}
+	require.NoError(t, lf.Save(filepath.Join(dir, name), licelformat.WithNegativeCounts()))
+}
+
+// loadNC — пак из NetCDF3-файла, записанного командой
+func loadNC(t *testing.T, fname string) *licelformat.LicelPack {
+	t.Helper()
+	pack, err := licelformat.LoadLicelPackFromNetCDF3(fname)
+	require.NoError(t, err)
+	require.Len(t, pack.Data, 1)
+	return pack
+}
+
+// onlyFile — единственный файл пака
+func onlyFile(t *testing.T, pack *licelformat.LicelPack) licelformat.LicelFile {
+	t.Helper()
+	require.Len(t, pack.Data, 1)
+	for _, lf := range pack.Data {
+		return lf
+	}
+	return licelformat.LicelFile{}
+}
+
+func TestRun(t *testing.T) {
+	raw, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T, dir string)
+		args   []string // $DIR заменяется временным каталогом
+		stdin  []byte
+		code   int
+		out    string // подстрока stdout
+		errOut string // подстрока stderr
+		noErr  bool   // stderr должен быть пуст
+		check  func(t *testing.T, dir, out string)
+	}{
+		{name: "no args", code: exitUsage, errOut: "Usage: licel"},
+		{name: "help", args: []string{"help"}, code: exitOK, out: "Commands:"},
+		{name: "unknown command", args: []string{"bogus"}, code: exitUsage, errOut: `unknown command "bogus"`},
+		{name: "command help", args: []string{"glue", "-h"}, code: exitOK, errOut: "-blend"},
+		{name: "bad flag", args: []string{"info", "-nope", testFile}, code: exitUsage},
+		{name: "no inputs", args: []string{"info"}, code: exitUsage, errOut: "no inputs given"},
+		{name: "missing input", args: []string{"info", "$DIR/none"}, code: exitFailure, errOut: "no files match"},
+		{name: "bad level", args: []string{"convert", "-level", "10", "-o", "$DIR/out.zip", testFile}, code: exitUsage, errOut: "-level"},
+
+		{name: "info", args: []string{"info", testFile}, code: exitOK, out: "Vladivos", noErr: true},
+		{name: "info short", args: []string{"info", "-short", testFile}, code: exitOK, out: "12 profiles"},
+		{name: "info stdin", args: []string{"info", "-short", "-"}, stdin: raw, code: exitOK, out: "stdin\tVladivos"},
+		{
+			name: "info json", args: []string{"info", "-json", testFile}, code: exitOK, out: `"variant": "classic"`,
+			check: func(t *testing.T, _, out string) {
+				assert.True(t, json.Valid([]byte(out)))
+			},
+		},
+		{name: "validate", args: []string{"validate", testFile}, code: exitOK, out: "OK\t"},
+
+		{
+			name: "convert to stdout", args: []string{"convert", "-o", "-", testFile}, code: exitOK,
+			check: func(t *testing.T, _, out string) {
+				assert.Equal(t, string(raw), out)
+			},
+		},
+		{
+			name: "convert stdin to stdout", args: []string{"convert", "-o", "-", "-"}, stdin: raw, code: exitOK,
+			check: func(t *testing.T, _, out string) {
+				assert.Equal(t, string(raw), out)
+			},
+		},
+		{
+			name: "convert to nc", args: []string{"convert", "-o", "$DIR/out.nc", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				assert.Len(t, onlyFile(t, loadNC(t, filepath.Join(dir, "out.nc"))).Profiles, 12)
+			},
+		},
+		{
+			name: "convert to zip", args: []string{"convert", "-level", "9", "-o", "$DIR/out.zip", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				pack, err := licelformat.NewLicelPackFromZip(filepath.Join(dir, "out.zip"))
+				require.NoError(t, err)
+				assert.Len(t, onlyFile(t, pack).Profiles, 12)
+			},
+		},
+		{
+			name: "convert to directory", args: []string{"convert", "-o", "$DIR/out", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				got, err := os.ReadFile(filepath.Join(dir, "out", filepath.Base(testFile)))
+				require.NoError(t, err)
+				assert.Equal(t, raw, got)
+			},
+		},
+		{
+			name:  "stdout holds one file",
+			setup: func(t *testing.T, dir string) { copyTestFile(t, dir, "b2021019.223600") },
+			args:  []string{"convert", "-o", "-", testFile, "$DIR/b2021019.223600"}, code: exitFailure, errOut: "single LICEL file",
+		},
+		{
+			name:  "merge",
+			setup: func(t *testing.T, dir string) { copyTestFile(t, dir, "b2021019.223600") },
+			args:  []string{"merge", "-o", "$DIR/all.zip", testFile, "$DIR/b2021019.223600"}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				pack, err := licelformat.NewLicelPackFromZip(filepath.Join(dir, "all.zip"))
+				require.NoError(t, err)
+				assert.Len(t, pack.Data, 2)
+			},
+		},
+
+		{
+			name: "trim", args: []string{"trim", "-max", "1000", "-o", "$DIR/t.nc", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				for _, pr := range onlyFile(t, loadNC(t, filepath.Join(dir, "t.nc"))).Profiles {
+					assert.Less(t, len(pr.Data), 16380)
+				}
+			},
+		},
+		{
+			name: "filter", args: []string{"filter", "-type", "photon", "-wl", "355", "-o", "$DIR/f.nc", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				prs := onlyFile(t, loadNC(t, filepath.Join(dir, "f.nc"))).Profiles
+				require.NotEmpty(t, prs)
+				for _, pr := range prs {
+					assert.True(t, pr.IsPhoton())
+					assert.Equal(t, 355.0, pr.Wavelength)
+				}
+			},
+		},
+
+		{
+			name: "glue", args: []string{"glue", "-wl", "355", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitOK, noErr: true,
+			check: func(t *testing.T, dir, _ string) {
+				prs := onlyFile(t, loadNC(t, filepath.Join(dir, "g.nc"))).Profiles
+				require.Len(t, prs, 13)
+				assert.True(t, prs[12].IsGlued())
+			},
+		},
+		{name: "glue range", args: []string{"glue", "-wl", "355", "-h1", "3000", "-h2", "1000", testFile}, code: exitUsage, errOut: "-h2"},
+		{name: "glue without wavelength", args: []string{"glue", "-h1", "1000", "-h2", "3000", testFile}, code: exitUsage, errOut: "-wl is required"},
+		{name: "glue missing channel", args: []string{"glue", "-wl", "999", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitFailure, errOut: "channel not found"},
+		{name: "glue align", args: []string{"glue", "-align", "-wl", "355", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitOK},
+		{
+			name: "glue dead time", args: []string{"glue", "-dead-time", "1", "-paralyzable", "-wl", "355", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				corrected := 0
+				for _, pr := range onlyFile(t, loadNC(t, filepath.Join(dir, "g.nc"))).Profiles {
+					if pr.IsPhoton() && pr.Wavelength == 355 {
+						assert.Equal(t, licelformat.DeadTimeParalyzable, pr.DeadTimeModel)
+						assert.Equal(t, 1.0, pr.DeadTime)
+						corrected++
+					}
+				}
+				assert.Positive(t, corrected)
+			},
+		},
+		{
+			name: "glue dead time limit", args: []string{"glue", "-dead-time", "3.7", "-paralyzable", "-wl", "355", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitFailure,
+			errOut: "reaches the paralyzable limit",
+			check: func(t *testing.T, dir, _ string) {
+				assert.NoFileExists(t, filepath.Join(dir, "g.nc"))
+			},
+		},
+		{name: "glue negative dead time", args: []string{"glue", "-dead-time", "-1", "-wl", "355", "-h1", "1000", "-h2", "3000", testFile}, code: exitUsage, errOut: "-dead-time"},
+		{
+			name: "glue auto regression", args: []string{"glue", "-auto", "-regression", "-v", "-wl", "355", "-o", "$DIR/g.nc", testFile}, code: exitOK,
+			errOut: "glue regression blend mean k=",
+		},
+		{name: "glue auto search bounds", args: []string{"glue", "-auto", "-wl", "355", "-h1", "3000", "-h2", "1000", testFile}, code: exitUsage, errOut: "-h2"},
+		{
+			name: "glue blend", args: []string{"glue", "-blend", "cosine", "-v", "-wl", "355", "-h1", "1000", "-h2", "3000", "-o", "$DIR/g.nc", testFile}, code: exitOK,
+			errOut: "glue ratio blend cosine",
+		},
+		{name: "glue bad blend", args: []string{"glue", "-blend", "bogus", "-wl", "355", "-h1", "1000", "-h2", "3000", testFile}, code: exitUsage, errOut: "-blend"},
+		{
+			name:  "glue unrepresentable",
+			setup: func(t *testing.T, dir string) { writeNegativeAnalog(t, dir, "neg") },
+			args:  []string{"glue", "-wl", "532", "-pol", "p", "-h1", "1000", "-h2", "3000", "-o", "-", "$DIR/neg"}, code: exitFailure,
+			errOut: "not representable",
+		},
+		{
+			name:  "glue clamp",
+			setup: func(t *testing.T, dir string) { writeNegativeAnalog(t, dir, "neg") },
+			args:  []string{"glue", "-clamp", "-wl", "532", "-pol", "p", "-h1", "1000", "-h2", "3000", "-o", "-", "$DIR/neg"}, code: exitOK,
+			errOut: "warning: profile 12: clamped",
+			check: func(t *testing.T, _, out string) {
+				lf, err := licelformat.LoadLicelFileFromReader(strings.NewReader(out))
+				require.NoError(t, err)
+				assert.Len(t, lf.Profiles, 13)
+			},
+		},
+		{
+			name:  "glue negative",
+			setup: func(t *testing.T, dir string) { writeNegativeAnalog(t, dir, "neg") },
+			args:  []string{"glue", "-negative", "-wl", "532", "-pol", "p", "-h1", "1000", "-h2", "3000", "-o", "-", "$DIR/neg"}, code: exitOK,
+			noErr: true,
+			check: func(t *testing.T, _, out string) {
+				lf, err := licelformat.LoadLicelFileFromReader(strings.NewReader(out))
+				require.NoError(t, err)
+				require.Len(t, lf.Profiles, 13)
+				assert.Less(t, slices.Min(lf.Profiles[12].Data), 0.0)
+			},
+		},
+
+		{
+			name: "rcs", args: []string{"rcs", "-bg", "median", "-bg-from", "20000", "-o", "$DIR/r.nc", testFile}, code: exitOK,
+			check: func(t *testing.T, dir, _ string) {
+				prs := onlyFile(t, loadNC(t, filepath.Join(dir, "r.nc"))).Profiles
+				require.Len(t, prs, 12)
+				for _, pr := range prs {
+					assert.True(t, pr.IsRangeCorrected())
+					assert.Equal(t, licelformat.BackgroundMedian, pr.Background.Method)
+				}
+			},
+		},
+		{name: "rcs without output", args: []string{"rcs", testFile}, code: exitUsage, errOut: "-o"},
+		{name: "rcs bad background", args: []string{"rcs", "-bg", "bogus", "-o", "$DIR/r.nc", testFile}, code: exitUsage, errOut: "-bg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if tt.setup != nil {
+				tt.setup(t, dir)
+			}
+			args := make([]string, len(tt.args))
+			for i, a := range tt.args {
+				args[i] = strings.ReplaceAll(a, "$DIR", dir)
+			}
+
+			code, out, errOut := runLicel(t, tt.stdin, args...)
+			assert.Equal(t, tt.code, code, "stderr: %s", errOut)
+			assert.Contains(t, out, tt.out)
+			assert.Contains(t, errOut, tt.errOut)
+			if tt.noErr {
+				assert.Empty(t, errOut)
+			}
+			if tt.check != nil && code == tt.code {
+				tt.check(t, dir, out)
+			}
+		})
+	}
+}
+
+func TestNewFlagSet_ConfigIsolated(t *testing.T) {
+	fs, cfg := newFlagSet("convert", "[flags] inputs...")
+	var output string
+	var level int
+	cfg.addOutputFlags(fs, &output, &level)
+	require.NoError(t, parseFlags(fs, []string{"-clamp", "-lenient", "-tz", "Local", testFile}))
+	assert.True(t, cfg.clamp)
+	assert.True(t, cfg.lenient)
+	assert.Equal(t, time.Local, cfg.location)
+
+	// параметры следующей подкоманды не зависят от предыдущей
+	_, next := newFlagSet("convert", "[flags] inputs...")
+	assert.Equal(t, newConfig(), next)
+	assert.Len(t, next.writeOptions("x"), 1)
+
+	pack, err := cfg.loadInputs(fs.Args())
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, onlyFile(t, pack).MeasurementStartTime.Location())
+}
@@ -0,0 +1,50 @@
+package main
+
+import "github.com/physicist2018/licelfile/v2/licelformat"
+
+// backgroundMethods — значения флага -bg
+var backgroundMethods = map[string]licelformat.BackgroundMethod{
+	"none":   licelformat.BackgroundNone,
+	"mean":   licelformat.BackgroundMean,
+	"median": licelformat.BackgroundMedian,
+	"linear": licelformat.BackgroundLinear,
+}
+
+// runRCS — сохраняет сигнал, исправленный на квадрат дальности, в NetCDF3
+func runRCS(args []string) error {
+	fs, cfg := newFlagSet("rcs", "-o out.nc [flags] inputs...")
+	var output, method string
+	var w licelformat.BackgroundWindow
+	fs.StringVar(&output, "o", "", "output NetCDF3 file, *.nc (required)")
+	fs.StringVar(&method, "bg", "none", "background subtracted before range correction: none, mean, median or linear")
+	fs.Float64Var(&w.From, "bg-from", 0, "start of the background window, m")
+	fs.Float64Var(&w.To, "bg-to", 0, "end of the background window, m (0 = last bin)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if !hasSuffixFold(output, ".nc") {
+		return usagef("-o must be a NetCDF3 file (*.nc), got %q", output)
+	}
+	m, ok := backgroundMethods[method]
+	if !ok {
+		return usagef("-bg must be none, mean, median or linear, got %q", method)
+	}
+	w.Method = m
+	if m != licelformat.BackgroundNone && w.From <= 0 {
+		return usagef("-bg-from is required with -bg %s", method)
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	pack, err := cfg.loadInputs(inputs)
+	if err != nil {
+		return err
+	}
+	rcs, err := pack.RangeCorrected(w)
+	if err != nil {
+		return err
+	}
+	return cfg.writeOutput(&rcs, output, 0)
+}
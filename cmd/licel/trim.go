@@ -0,0 +1,33 @@
+package main
+
+// runTrim — обрезает все профили входов до максимальной дальности
+func runTrim(args []string) error {
+	fs, cfg := newFlagSet("trim", "-max m [flags] inputs...")
+	var output string
+	var level int
+	var maxDist float64
+	cfg.addOutputFlags(fs, &output, &level)
+	fs.Float64Var(&maxDist, "max", 0, "maximum range, m (required)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if err := checkLevel(level); err != nil {
+		return err
+	}
+	if maxDist <= 0 {
+		return usagef("-max must be positive")
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	pack, err := cfg.loadInputs(inputs)
+	if err != nil {
+		return err
+	}
+	if err := pack.SetMaxDist(maxDist); err != nil {
+		return err
+	}
+	return cfg.writeOutput(pack, output, level)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/physicist2018/licelfile/v2/licelformat"
+)
+
+// runValidate — проверяет, что все входы читаются без ошибок и проходят
+// LicelPack.Validate. Глоб-маски проверяются по файлам, чтобы один битый файл
+// не скрывал остальные; согласованность между файлами проверяется по всем входам.
+func runValidate(args []string) error {
+	fs, cfg := newFlagSet("validate", "[flags] inputs...")
+	var quiet, strict bool
+	fs.BoolVar(&quiet, "q", false, "print only failures and issues")
+	fs.BoolVar(&strict, "strict", false, "treat warnings as failures")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	inputs, err := requireInputs(fs)
+	if err != nil {
+		return err
+	}
+
+	failSeverity := licelformat.SeverityError
+	if strict {
+		failSeverity = licelformat.SeverityWarning
+	}
+
+	// Загрузка: ошибки разбора запоминаются по имени, загруженные файлы собираются в пак
+	var names []string
+	loadErrs := make(map[string]error)
+	pack := &licelformat.LicelPack{Data: make(map[string]licelformat.LicelFile)}
+	for _, input := range inputs {
+		if input == "-" || hasSuffixFold(input, ".zip") || hasSuffixFold(input, ".nc") {
+			p, err := readInput(input, cfg.loadOptions())
+			if err != nil {
+				names = append(names, input)
+				loadErrs[input] = err
+				continue
+			}
+			names = append(names, p.Names()...)
+			pack.Merge(p)
+			continue
+		}
+
+		files, err := filepath.Glob(input)
+		if err != nil {
+			return usagef("bad glob %q: %v", input, err)
+		}
+		if len(files) == 0 {
+			names = append(names, input)
+			loadErrs[input] = fmt.Errorf("no files match")
+			continue
+		}
+		for _, fname := range files {
+			names = append(names, fname)
+			lf, err := licelformat.LoadLicelFile(fname, cfg.loadOptions()...)
+			if err != nil {
+				loadErrs[fname] = err
+				continue
+			}
+			pack.Data[fname] = lf
+		}
+	}
+
+	report := pack.Validate()
+	byFile := make(map[string][]licelformat.ValidationIssue)
+	for _, vi := range report.Issues {
+		byFile[vi.File] = append(byFile[vi.File], vi)
+	}
+
+	failed := 0
+	check := func(name string, issues []licelformat.ValidationIssue) {
+		fail := false
+		for _, vi := range issues {
+			if vi.Severity >= failSeverity {
+				fail = true
+			}
+		}
+		if fail {
+			failed++
+			fmt.Fprintf(stdout, "FAIL\t%s\n", name)
+		} else if !quiet {
+			fmt.Fprintf(stdout, "OK\t%s\n", name)
+		}
+		for _, vi := range issues {
+			fmt.Fprintf(stdout, "\t%s\t%s\n", strings.ToUpper(vi.Severity.String()), issueText(vi))
+		}
+	}
+
+	for _, name := range names {
+		if err, ok := loadErrs[name]; ok {
+			failed++
+			fmt.Fprintf(stdout, "FAIL\t%s\t%v\n", name, err)
+			continue
+		}
+		check(name, byFile[name])
+	}
+	if issues := byFile[""]; len(issues) > 0 {
+		check("(all inputs)", issues)
+	}
+
+	if failed > 0 {
+		return &silentError{code: exitFailure}
+	}
+	return nil
+}
+
+// issueText — описание проблемы без уровня и имени файла (они выводятся отдельно)
+func issueText(vi licelformat.ValidationIssue) string {
+	var parts []string
+	if vi.Profile >= 0 {
+		parts = append(parts, fmt.Sprintf("profile %d", vi.Profile))
+	}
+	if vi.Field != "" {
+		parts = append(parts, vi.Field)
+	}
+	parts = append(parts, vi.Message)
+	return strings.Join(parts, ": ")
+}
@@ -0,0 +1,276 @@
+package licelformat
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// BackgroundMethod — способ оценки фона по дальнему участку профиля
+type BackgroundMethod int
+
+const (
+	BackgroundNone   BackgroundMethod = iota // фон не вычитался
+	BackgroundMean                           // среднее по окну
+	BackgroundMedian                         // медиана по окну
+	BackgroundLinear                         // прямая по дальности (МНК), вычитается во всех бинах
+)
+
+// String — название способа
+func (m BackgroundMethod) String() string {
+	switch m {
+	case BackgroundNone:
+		return "none"
+	case BackgroundMean:
+		return "mean"
+	case BackgroundMedian:
+		return "median"
+	case BackgroundLinear:
+		return "linear"
+	}
+	return fmt.Sprintf("BackgroundMethod(%d)", int(m))
+}
+
+// MarshalText — способ в JSON выводится названием
+func (m BackgroundMethod) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText — способ по названию из MarshalText
+func (m *BackgroundMethod) UnmarshalText(text []byte) error {
+	for v := BackgroundNone; v <= BackgroundLinear; v++ {
+		if v.String() == string(text) {
+			*m = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown background method %q", text)
+}
+
+// BackgroundWindow — окно дальностей и способ оценки фона
+type BackgroundWindow struct {
+	Method BackgroundMethod `json:"method"`
+	From   float64          `json:"from"` // начало окна, м
+	To     float64          `json:"to"`   // конец окна включительно, м; 0 — до последнего бина
+}
+
+// Background — оценка фона профиля в единицах Data
+type Background struct {
+	BackgroundWindow
+	Level float64 `json:"level"`           // фон на нулевой дальности (для среднего и медианы — во всех бинах)
+	Slope float64 `json:"slope,omitempty"` // наклон фона на метр дальности (BackgroundLinear)
+	Std   float64 `json:"std"`             // СКО значений окна относительно оценки
+	Bins  int     `json:"bins"`            // число бинов окна, вошедших в оценку
+}
+
+// At — фон на дальности r, м
+func (b Background) At(r float64) float64 {
+	return b.Level + b.Slope*r
+}
+
+// window — индексы [i1, i2] бинов окна w в профиле
+func (lp *LicelProfile) window(w BackgroundWindow) (int, int, error) {
+	if lp.BinWidth <= 0 {
+		return 0, 0, fmt.Errorf("bin width must be positive, got %.2f", lp.BinWidth)
+	}
+	n := len(lp.Data)
+	i1 := max(lp.RangeIndex(w.From), 0)
+	i2 := n - 1
+	if w.To > 0 {
+		i2 = lp.RangeIndex(w.To)
+	}
+	if i2 >= n {
+		return 0, 0, fmt.Errorf("window end %.2f m maps to bin %d, exceeds data length %d", w.To, i2, n)
+	}
+	if i1 > i2 {
+		return 0, 0, fmt.Errorf("window [%.2f, %.2f] m maps to empty bin range [%d, %d]", w.From, w.To, i1, i2)
+	}
+	return i1, i2, nil
+}
+
+// EstimateBackground — оценивает фон по окну w, не изменяя профиль. Значения NaN в окне
+// пропускаются; нужно не меньше 2 значений (3 для BackgroundLinear).
+func (lp *LicelProfile) EstimateBackground(w BackgroundWindow) (Background, error) {
+	i1, i2, err := lp.window(w)
+	if err != nil {
+		return Background{}, fmt.Errorf("EstimateBackground: %w", err)
+	}
+	ranges := lp.Ranges()
+	var r, v []float64
+	for i := i1; i <= i2; i++ {
+		if math.IsNaN(lp.Data[i]) {
+			continue
+		}
+		r = append(r, ranges[i])
+		v = append(v, lp.Data[i])
+	}
+
+	b := Background{BackgroundWindow: w, Bins: len(v)}
+	dof := len(v) - 1
+	switch w.Method {
+	case BackgroundMean:
+		b.Level = mean(v)
+	case BackgroundMedian:
+		b.Level = median(v)
+	case BackgroundLinear:
+		dof--
+		b.Slope, b.Level = linearFit(r, v)
+	default:
+		return Background{}, fmt.Errorf("EstimateBackground: unknown method %s", w.Method)
+	}
+	if dof < 1 {
+		return Background{}, fmt.Errorf("EstimateBackground: %d values in window [%d, %d] are too few for %s", len(v), i1, i2, w.Method)
+	}
+	var ss float64
+	for i := range v {
+		d := v[i] - b.At(r[i])
+		ss += d * d
+	}
+	b.Std = math.Sqrt(ss / float64(dof))
+	return b, nil
+}
+
+// SubtractBackground — оценивает фон по окну w (см. EstimateBackground) и вычитает его
+// из всех бинов Data. Оценка сохраняется в поле Background и возвращается RestoreBackground.
+// Повторное вычитание — ошибка. Отрицательные после вычитания значения записываются
+// в LICEL-файл только с WithNegativeCounts или WithClamp.
+func (lp *LicelProfile) SubtractBackground(w BackgroundWindow) error {
+	apply, err := lp.backgroundSubtraction(w)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// backgroundSubtraction — оценивает фон для SubtractBackground и возвращает функцию,
+// которая его вычитает
+func (lp *LicelProfile) backgroundSubtraction(w BackgroundWindow) (func(), error) {
+	if lp.Background.Method != BackgroundNone {
+		return nil, fmt.Errorf("SubtractBackground: background already subtracted (%s)", lp.Background.Method)
+	}
+	b, err := lp.EstimateBackground(w)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		lp.addBackground(b, -1)
+		lp.Background = b
+	}, nil
+}
+
+// RestoreBackground — возвращает в Data фон, вычтенный SubtractBackground.
+// Для профилей RangeCorrected Background только описывает вычтенный до умножения на r² фон.
+func (lp *LicelProfile) RestoreBackground() error {
+	if lp.Background.Method == BackgroundNone {
+		return fmt.Errorf("RestoreBackground: background was not subtracted")
+	}
+	if lp.IsRangeCorrected() {
+		return fmt.Errorf("RestoreBackground: background of a range-corrected profile cannot be restored")
+	}
+	lp.addBackground(lp.Background, 1)
+	lp.Background = Background{}
+	return nil
+}
+
+// addBackground — прибавляет к Data фон b с множителем sign
+func (lp *LicelProfile) addBackground(b Background, sign float64) {
+	if b.Slope == 0 {
+		for i := range lp.Data {
+			lp.Data[i] += sign * b.Level
+		}
+		return
+	}
+	zero := lp.ZeroBin()
+	for i := range lp.Data {
+		lp.Data[i] += sign * b.At((float64(i)-zero)*lp.BinWidth)
+	}
+}
+
+// SubtractBackground — вычитает фон из всех профилей файла по одному окну w.
+// Фон сначала оценивается для всех профилей: при ошибке файл не изменяется.
+func (lf *LicelFile) SubtractBackground(w BackgroundWindow) error {
+	updates, err := lf.backgroundSubtractions(w)
+	if err != nil {
+		return err
+	}
+	applyUpdates(updates)
+	return nil
+}
+
+// backgroundSubtractions — оценённые вычитания фона всех профилей файла
+func (lf *LicelFile) backgroundSubtractions(w BackgroundWindow) ([]func(), error) {
+	updates := make([]func(), 0, len(lf.Profiles))
+	for i := range lf.Profiles {
+		apply, err := lf.Profiles[i].backgroundSubtraction(w)
+		if err != nil {
+			return nil, fmt.Errorf("profile %d: %w", i, err)
+		}
+		updates = append(updates, apply)
+	}
+	return updates, nil
+}
+
+// RestoreBackground — возвращает вычтенный фон профилям файла; профили без вычтенного фона
+// и профили RangeCorrected пропускаются
+func (lf *LicelFile) RestoreBackground() {
+	for i := range lf.Profiles {
+		if lf.Profiles[i].Background.Method != BackgroundNone && !lf.Profiles[i].IsRangeCorrected() {
+			_ = lf.Profiles[i].RestoreBackground()
+		}
+	}
+}
+
+// SubtractBackground — вычитает фон из всех профилей во всех файлах пака по одному окну w.
+// При ошибке в любом файле пак не изменяется.
+func (lp *LicelPack) SubtractBackground(w BackgroundWindow) error {
+	return lp.updateFiles(func(_ string, lf *LicelFile) ([]func(), error) {
+		return lf.backgroundSubtractions(w)
+	})
+}
+
+// RestoreBackground — возвращает вычтенный фон профилям всех файлов пака
+func (lp *LicelPack) RestoreBackground() {
+	for fname, licf := range lp.Data {
+		licf.RestoreBackground()
+		lp.Data[fname] = licf
+	}
+}
+
+// mean — среднее значение
+func mean(v []float64) float64 {
+	var s float64
+	for _, x := range v {
+		s += x
+	}
+	return s / float64(len(v))
+}
+
+// median — медиана; v не изменяется
+func median(v []float64) float64 {
+	if len(v) == 0 {
+		return math.NaN()
+	}
+	s := slices.Clone(v)
+	slices.Sort(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return 0.5 * (s[n/2-1] + s[n/2])
+}
+
+// linearFit — наклон и свободный член прямой y = slope·x + icept по МНК
+func linearFit(x, y []float64) (slope, icept float64) {
+	mx, my := mean(x), mean(y)
+	var sxy, sxx float64
+	for i := range x {
+		sxy += (x[i] - mx) * (y[i] - my)
+		sxx += (x[i] - mx) * (x[i] - mx)
+	}
+	if sxx == 0 {
+		return 0, my
+	}
+	slope = sxy / sxx
+	return slope, my - slope*mx
+}
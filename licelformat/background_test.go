@@ -0,0 +1,160 @@
+package licelformat
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backgroundTestProfile — 10 бинов по 10 м: сигнал в первых пяти, фон 5 ± 1 в остальных
+func backgroundTestProfile() LicelProfile {
+	data := []float64{100, 80, 60, 40, 20, 4, 6, 4, 6, 5}
+	return LicelProfile{BinWidth: 10, NDataPoints: len(data), Data: data}
+}
+
+func TestBackgroundMethod_String(t *testing.T) {
+	assert.Equal(t, "none", BackgroundNone.String())
+	assert.Equal(t, "mean", BackgroundMean.String())
+	assert.Equal(t, "median", BackgroundMedian.String())
+	assert.Equal(t, "linear", BackgroundLinear.String())
+	assert.Equal(t, "BackgroundMethod(9)", BackgroundMethod(9).String())
+}
+
+func TestLicelProfile_EstimateBackground(t *testing.T) {
+	pr := backgroundTestProfile()
+
+	b, err := pr.EstimateBackground(BackgroundWindow{Method: BackgroundMean, From: 50})
+	require.NoError(t, err)
+	assert.InDelta(t, 5, b.Level, 1e-12)
+	assert.InDelta(t, 1, b.Std, 1e-12)
+	assert.Equal(t, 5, b.Bins)
+
+	b, err = pr.EstimateBackground(BackgroundWindow{Method: BackgroundMedian, From: 50, To: 80})
+	require.NoError(t, err)
+	assert.Equal(t, 5.0, b.Level)
+	assert.Equal(t, 4, b.Bins)
+
+	// NaN в окне пропускается
+	pr.Data[9] = math.NaN()
+	b, err = pr.EstimateBackground(BackgroundWindow{Method: BackgroundMean, From: 50})
+	require.NoError(t, err)
+	assert.Equal(t, 4, b.Bins)
+	assert.InDelta(t, 5, b.Level, 1e-12)
+}
+
+func TestLicelProfile_EstimateBackground_Linear(t *testing.T) {
+	pr := LicelProfile{BinWidth: 10, BinShift: 1, NDataPoints: 6, Data: []float64{0, 50, 3, 5, 7, 9}}
+	b, err := pr.EstimateBackground(BackgroundWindow{Method: BackgroundLinear, From: 10})
+	require.NoError(t, err)
+	// дальности окна 10..40 м, значения 3..9: фон 1 + 0.2·r
+	assert.InDelta(t, 0.2, b.Slope, 1e-12)
+	assert.InDelta(t, 1, b.Level, 1e-12)
+	assert.InDelta(t, 0, b.Std, 1e-12)
+	assert.InDelta(t, 9, b.At(40), 1e-12)
+}
+
+func TestLicelProfile_EstimateBackground_Errors(t *testing.T) {
+	pr := backgroundTestProfile()
+	for name, w := range map[string]BackgroundWindow{
+		"method":   {From: 50},
+		"beyond":   {Method: BackgroundMean, From: 50, To: 500},
+		"empty":    {Method: BackgroundMean, From: 80, To: 50},
+		"one bin":  {Method: BackgroundMean, From: 90},
+		"two bins": {Method: BackgroundLinear, From: 80},
+	} {
+		_, err := pr.EstimateBackground(w)
+		assert.Error(t, err, name)
+	}
+	_, err := (&LicelProfile{Data: []float64{1, 2}}).EstimateBackground(BackgroundWindow{Method: BackgroundMean})
+	assert.Error(t, err)
+}
+
+func TestLicelProfile_SubtractBackground_Restore(t *testing.T) {
+	pr := LicelProfile{BinWidth: 10, NDataPoints: 6, Data: []float64{50, 40, 3, 5, 7, 9}}
+	orig := append([]float64(nil), pr.Data...)
+	w := BackgroundWindow{Method: BackgroundLinear, From: 20}
+
+	require.NoError(t, pr.SubtractBackground(w))
+	assert.InDeltaSlice(t, []float64{51, 39, 0, 0, 0, 0}, pr.Data, 1e-9)
+	assert.Equal(t, w, pr.Background.BackgroundWindow)
+	assert.Error(t, pr.SubtractBackground(w))
+
+	require.NoError(t, pr.RestoreBackground())
+	assert.InDeltaSlice(t, orig, pr.Data, 1e-9)
+	assert.Equal(t, BackgroundNone, pr.Background.Method)
+	assert.Error(t, pr.RestoreBackground())
+}
+
+func TestLicelProfile_CorrectDeadTime_AfterBackground(t *testing.T) {
+	pr := backgroundTestProfile()
+	pr.Photon = true
+	require.NoError(t, pr.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 50}))
+	assert.ErrorContains(t, pr.CorrectDeadTime(4, DeadTimeNonParalyzable), "background")
+}
+
+func TestLicelPack_SubtractBackground(t *testing.T) {
+	pack := testPack(backgroundTestProfile(), backgroundTestProfile())
+	require.NoError(t, pack.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 50}))
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		for _, pr := range lf.Profiles {
+			assert.InDelta(t, 95, pr.Data[0], 1e-12)
+			assert.InDelta(t, 5, pr.Background.Level, 1e-12)
+		}
+	})
+
+	pack.RestoreBackground()
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		for _, pr := range lf.Profiles {
+			assert.Equal(t, backgroundTestProfile().Data, pr.Data)
+			assert.Equal(t, Background{}, pr.Background)
+		}
+	})
+
+	assert.Error(t, pack.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 500}))
+}
+
+func TestLicelPack_SubtractBackground_Atomic(t *testing.T) {
+	pack := testPack(backgroundTestProfile(), backgroundTestProfile())
+	lf := pack.Data["/data/b"]
+	lf.Profiles[1].Data, lf.Profiles[1].NDataPoints = lf.Profiles[1].Data[:4], 4 // окно от 50 м пусто
+
+	err := pack.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 50})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/data/b: profile 1")
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, backgroundTestProfile().Data, lf.Profiles[0].Data)
+		assert.Equal(t, Background{}, lf.Profiles[0].Background)
+	})
+
+	require.Error(t, lf.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 50}))
+	assert.Equal(t, BackgroundNone, lf.Profiles[0].Background.Method)
+}
+
+func TestLicelProfile_Background_Exported(t *testing.T) {
+	pr := backgroundTestProfile()
+	pr.Polarization, pr.DeviceID, pr.NShots = "o", DeviceIDAnalog, 10
+	require.NoError(t, pr.SubtractBackground(BackgroundWindow{Method: BackgroundMedian, From: 50}))
+
+	b, err := json.Marshal(pr)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"background":{"method":"median","from":50,"to":0,"level":5,"std":1,"bins":5}`)
+	b, err = json.Marshal(backgroundTestProfile())
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), `"background"`)
+
+	pack := &LicelPack{Data: map[string]LicelFile{
+		"/data/a": {MeasurementSite: "Site", NDatasets: 1, Profiles: LicelProfilesList{pr}},
+	}}
+	fname := filepath.Join(t.TempDir(), "background.nc")
+	require.NoError(t, pack.SaveToNetCDF3(fname))
+	loaded, err := LoadLicelPackFromNetCDF3(fname)
+	require.NoError(t, err)
+	got := loaded.Data["/data/a"].Profiles[0]
+	assert.Equal(t, pr.Background, got.Background)
+	require.NoError(t, got.RestoreBackground())
+	assert.InDeltaSlice(t, backgroundTestProfile().Data, got.Data, 1e-12)
+}
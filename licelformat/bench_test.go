@@ -0,0 +1,106 @@
+package licelformat
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Бенчмарки декодирования и записи данных профилей на файле из testdata.
+// Для пака из тысяч файлов важны ns/op и allocs/op на один файл:
+//
+//	go test -bench . -benchmem ./licelformat
+
+func benchTestdata(b *testing.B) []byte {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return raw
+}
+
+func BenchmarkLoadLicelFileFromReader(b *testing.B) {
+	raw := benchTestdata(b)
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadLicelFileFromReader(bytes.NewReader(raw)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadLicelFileFromReader_WithoutRaw(b *testing.B) {
+	raw := benchTestdata(b)
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithoutRaw()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLicelFile_WriteTo(b *testing.B) {
+	raw := benchTestdata(b)
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := lf.WriteTo(io.Discard, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLicelFile_WriteTo_Modified(b *testing.B) {
+	// Изменённые данные кодируются делением на масштаб, а не копированием Raw
+	raw := benchTestdata(b)
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithoutRaw())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(raw)))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := lf.WriteTo(io.Discard, ""); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLicelReader_ReadProfile(b *testing.B) {
+	raw := benchTestdata(b)
+	lr, err := NewLicelReader(bytes.NewReader(raw))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(lr.header.Profiles[0].NDataPoints * 4))
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := lr.ReadProfile(0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLicelReader_ReadProfileInto(b *testing.B) {
+	raw := benchTestdata(b)
+	lr, err := NewLicelReader(bytes.NewReader(raw))
+	if err != nil {
+		b.Fatal(err)
+	}
+	var pr LicelProfile
+	b.SetBytes(int64(lr.header.Profiles[0].NDataPoints * 4))
+	b.ReportAllocs()
+	for b.Loop() {
+		if err := lr.ReadProfileInto(0, &pr); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
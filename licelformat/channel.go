@@ -0,0 +1,32 @@
+package licelformat
+
+import "fmt"
+
+// Channel — выбор каналов лидара для поканальных поправок. Пустое поле подходит под любое значение.
+type Channel struct {
+	Wavelength   float64 `json:"wavelength,omitempty"`   // длина волны, нм; 0 — любая
+	Polarization string  `json:"polarization,omitempty"` // поляризация; "" — любая
+	DeviceID     string  `json:"device_id,omitempty"`    // тип канала (DeviceIDAnalog, DeviceIDPhoton, ...); "" — любой
+}
+
+// Match — подходит ли профиль lp под выбор
+func (c Channel) Match(lp *LicelProfile) bool {
+	return (c.Wavelength == 0 || c.Wavelength == lp.Wavelength) &&
+		(c.Polarization == "" || c.Polarization == lp.Polarization) &&
+		(c.DeviceID == "" || c.DeviceID == lp.DeviceID)
+}
+
+// String — описание выбора, например "355.o BC" или "* * BT"
+func (c Channel) String() string {
+	wl, pol, dev := "*", "*", "*"
+	if c.Wavelength != 0 {
+		wl = fmt.Sprintf("%g", c.Wavelength)
+	}
+	if c.Polarization != "" {
+		pol = c.Polarization
+	}
+	if c.DeviceID != "" {
+		dev = c.DeviceID
+	}
+	return fmt.Sprintf("%s.%s %s", wl, pol, dev)
+}
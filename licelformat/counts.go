@@ -0,0 +1,140 @@
+package licelformat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotRepresentable — значение профиля нельзя записать в целочисленном формате LICEL
+// (NaN, бесконечность, отрицательный или не помещающийся в int32 отсчёт, неверный масштаб).
+// Проверяется через errors.Is.
+var ErrNotRepresentable = errors.New("not representable as LICEL counts")
+
+// RoundingMode — способ округления Data/scale до целого отсчёта при записи
+type RoundingMode int
+
+const (
+	RoundNearest  RoundingMode = iota // к ближайшему, половины от нуля (по умолчанию)
+	RoundTruncate                     // отбрасывание дробной части (к нулю)
+	RoundFloor                        // вниз
+	RoundCeil                         // вверх
+)
+
+// String — название способа округления
+func (m RoundingMode) String() string {
+	switch m {
+	case RoundNearest:
+		return "nearest"
+	case RoundTruncate:
+		return "truncate"
+	case RoundFloor:
+		return "floor"
+	case RoundCeil:
+		return "ceil"
+	}
+	return fmt.Sprintf("RoundingMode(%d)", int(m))
+}
+
+// round — округляет x способом m
+func (m RoundingMode) round(x float64) float64 {
+	switch m {
+	case RoundTruncate:
+		return math.Trunc(x)
+	case RoundFloor:
+		return math.Floor(x)
+	case RoundCeil:
+		return math.Ceil(x)
+	}
+	return math.Round(x)
+}
+
+// ClampReport — отсчёты профиля, приведённые к допустимому диапазону при записи с WithClamp
+type ClampReport struct {
+	Profile int `json:"profile"` // индекс профиля в файле
+	NaN     int `json:"nan"`     // NaN, записаны как 0
+	Below   int `json:"below"`   // меньше нижней границы (0 или math.MinInt32), записаны как граница
+	Above   int `json:"above"`   // больше math.MaxInt32, записаны как math.MaxInt32
+}
+
+// Total — общее число приведённых отсчётов
+func (r ClampReport) Total() int {
+	return r.NaN + r.Below + r.Above
+}
+
+// String — описание в одну строку
+func (r ClampReport) String() string {
+	return fmt.Sprintf("profile %d: clamped %d samples (%d NaN, %d below range, %d above range)",
+		r.Profile, r.Total(), r.NaN, r.Below, r.Above)
+}
+
+// countConverter — перевод масштабированных значений в отсчёты по правилам WriteOptions
+type countConverter struct {
+	scale    float64
+	rounding RoundingMode
+	clamp    bool    // приводить к диапазону вместо ошибки (задан OnClamp)
+	min      float64 // нижняя граница отсчёта
+	report   ClampReport
+}
+
+// newCountConverter — конвертер для профиля lp; ошибка, если масштаб профиля непригоден для деления
+func newCountConverter(lp *LicelProfile, o *WriteOptions) (*countConverter, error) {
+	scale := lp.scaleFactor()
+	if scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return nil, fmt.Errorf("scale factor %g (n shots %d): %w", scale, lp.NShots, ErrNotRepresentable)
+	}
+	c := &countConverter{scale: scale, rounding: o.Rounding, clamp: o.OnClamp != nil}
+	if o.NegativeCounts {
+		c.min = math.MinInt32
+	}
+	return c, nil
+}
+
+// count — отсчёт для значения v с индексом i. Вне диапазона [min, MaxInt32] и для NaN —
+// ошибка ErrNotRepresentable либо, с Clamp, граница диапазона (NaN → 0) с учётом в report.
+func (c *countConverter) count(i int, v float64) (int32, error) {
+	x := c.rounding.round(v / c.scale)
+	switch {
+	case math.IsNaN(x):
+		if !c.clamp {
+			return 0, fmt.Errorf("sample %d is NaN: %w", i, ErrNotRepresentable)
+		}
+		c.report.NaN++
+		return 0, nil
+	case x < c.min:
+		if !c.clamp {
+			return 0, fmt.Errorf("sample %d = %g (%g counts) is below %g: %w", i, v, x, c.min, ErrNotRepresentable)
+		}
+		c.report.Below++
+		return int32(c.min), nil
+	case x > math.MaxInt32:
+		if !c.clamp {
+			return 0, fmt.Errorf("sample %d = %g (%g counts) exceeds %d: %w", i, v, x, math.MaxInt32, ErrNotRepresentable)
+		}
+		c.report.Above++
+		return math.MaxInt32, nil
+	}
+	return int32(x), nil
+}
+
+// Counts — отсчёты, которые будут записаны в файл для данных профиля.
+// Учитываются WithRounding, WithClamp и WithNegativeCounts; нетронутые после загрузки
+// отсчёты берутся из Raw без пересчёта. Ошибка оборачивает ErrNotRepresentable.
+// Поле Profile отчёта, переданного в WithClamp, равно -1.
+func (lp *LicelProfile) Counts(opts ...WriteOption) ([]int32, error) {
+	o := newWriteOptions(opts)
+	b, report, err := lp.appendRaw(nil, &o)
+	if err != nil {
+		return nil, err
+	}
+	if report.Total() > 0 {
+		report.Profile = -1
+		o.OnClamp(report)
+	}
+	counts := make([]int32, len(lp.Data))
+	for i := range counts {
+		counts[i] = int32(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return counts, nil
+}
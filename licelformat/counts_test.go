@@ -0,0 +1,107 @@
+package licelformat
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countsTestProfile — фотонный профиль с масштабом 1 (NShots·0.05 мкс == 1 при BinWidth по умолчанию)
+func countsTestProfile(data ...float64) LicelProfile {
+	return LicelProfile{Photon: true, NShots: 20, NDataPoints: len(data), Data: data}
+}
+
+func TestRoundingMode_String(t *testing.T) {
+	assert.Equal(t, "nearest", RoundNearest.String())
+	assert.Equal(t, "truncate", RoundTruncate.String())
+	assert.Equal(t, "floor", RoundFloor.String())
+	assert.Equal(t, "ceil", RoundCeil.String())
+	assert.Equal(t, "RoundingMode(9)", RoundingMode(9).String())
+}
+
+func TestLicelProfile_Counts_Rounding(t *testing.T) {
+	pr := countsTestProfile(1.4, 1.5, 2.7, -0.0)
+	for _, tc := range []struct {
+		mode RoundingMode
+		want []int32
+	}{
+		{RoundNearest, []int32{1, 2, 3, 0}},
+		{RoundTruncate, []int32{1, 1, 2, 0}},
+		{RoundFloor, []int32{1, 1, 2, 0}},
+		{RoundCeil, []int32{2, 2, 3, 0}},
+	} {
+		counts, err := pr.Counts(WithRounding(tc.mode))
+		require.NoError(t, err, tc.mode)
+		assert.Equal(t, tc.want, counts, tc.mode)
+	}
+}
+
+func TestLicelProfile_Counts_NearestFixesRoundoff(t *testing.T) {
+	// Без Raw значение 7*scale/scale может оказаться чуть меньше 7
+	pr := LicelProfile{AdcBits: 12, NShots: 2001, DiscrLevel: 0.5}
+	pr.decodeData([]byte{7, 0, 0, 0, 9, 0, 0, 0, 11, 0, 0, 0})
+	want := pr.Raw
+	pr.Raw = nil
+
+	counts, err := pr.Counts()
+	require.NoError(t, err)
+	assert.Equal(t, want, counts)
+}
+
+func TestLicelProfile_Counts_NotRepresentable(t *testing.T) {
+	for name, v := range map[string]float64{
+		"NaN":      math.NaN(),
+		"negative": -3,
+		"overflow": 3e9,
+		"+Inf":     math.Inf(1),
+	} {
+		pr := countsTestProfile(1, v)
+		_, err := pr.Counts()
+		assert.ErrorIs(t, err, ErrNotRepresentable, name)
+		assert.ErrorContains(t, err, "sample 1", name)
+	}
+
+	pr := countsTestProfile(1)
+	pr.NShots = 0
+	_, err := pr.Counts()
+	assert.ErrorIs(t, err, ErrNotRepresentable)
+	assert.ErrorContains(t, err, "scale factor")
+}
+
+func TestLicelProfile_Counts_Clamp(t *testing.T) {
+	pr := countsTestProfile(5, math.NaN(), -3, 3e9, math.Inf(-1))
+	var reports []ClampReport
+	counts, err := pr.Counts(WithClamp(func(r ClampReport) { reports = append(reports, r) }))
+	require.NoError(t, err)
+	assert.Equal(t, []int32{5, 0, 0, math.MaxInt32, 0}, counts)
+	require.Len(t, reports, 1)
+	assert.Equal(t, ClampReport{Profile: -1, NaN: 1, Below: 2, Above: 1}, reports[0])
+	assert.Equal(t, "profile -1: clamped 4 samples (1 NaN, 2 below range, 1 above range)", reports[0].String())
+
+	counts, err = pr.Counts(WithClamp(nil), WithNegativeCounts())
+	require.NoError(t, err)
+	assert.Equal(t, []int32{5, 0, -3, math.MaxInt32, math.MinInt32}, counts)
+}
+
+func TestLicelFile_WriteTo_NotRepresentable(t *testing.T) {
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes("02")))
+	require.NoError(t, err)
+	lf.Profiles[1].Data[2] = -lf.Profiles[1].Data[2]
+
+	var buf bytes.Buffer
+	err = lf.WriteTo(&buf, "")
+	assert.ErrorIs(t, err, ErrNotRepresentable)
+	assert.ErrorContains(t, err, "serializing profile 1: sample 2")
+
+	var reports []ClampReport
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, "", WithClamp(func(r ClampReport) { reports = append(reports, r) })))
+	assert.Equal(t, []ClampReport{{Profile: 1, Below: 1}}, reports)
+
+	lf2, err := LoadLicelFileFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []int32{4, 5, 0}, lf2.Profiles[1].Raw)
+}
@@ -0,0 +1,175 @@
+package licelformat
+
+import (
+	"fmt"
+	"math"
+)
+
+// DeadTimeModel — модель мёртвого времени счётчика фотонов
+type DeadTimeModel int
+
+const (
+	DeadTimeNone           DeadTimeModel = iota // поправка не применялась
+	DeadTimeNonParalyzable                      // непродлевающееся: M = N/(1 + N·τ)
+	DeadTimeParalyzable                         // продлевающееся: M = N·exp(−N·τ)
+)
+
+// String — название модели
+func (m DeadTimeModel) String() string {
+	switch m {
+	case DeadTimeNone:
+		return "none"
+	case DeadTimeNonParalyzable:
+		return "non-paralyzable"
+	case DeadTimeParalyzable:
+		return "paralyzable"
+	}
+	return fmt.Sprintf("DeadTimeModel(%d)", int(m))
+}
+
+// MarshalText — модель в JSON выводится названием
+func (m DeadTimeModel) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText — модель по названию из MarshalText
+func (m *DeadTimeModel) UnmarshalText(text []byte) error {
+	for v := DeadTimeNone; v <= DeadTimeParalyzable; v++ {
+		if v.String() == string(text) {
+			*m = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown dead time model %q", text)
+}
+
+// DeadTime — мёртвое время фотонных каналов, выбранных Channel
+type DeadTime struct {
+	Channel
+	Tau   float64       `json:"tau"`   // мёртвое время, нс
+	Model DeadTimeModel `json:"model"` // модель счётчика
+}
+
+// paralyzableIterations — предел итераций Ньютона для продлевающейся модели
+const paralyzableIterations = 100
+
+// CorrectDeadTime — восстанавливает истинную скорость счёта фотонного канала по измеренной
+// (Data в МГц) для мёртвого времени tau (нс) и модели model. Неположительные значения и NaN
+// не изменяются. Если скорость в каком-либо бине не меньше предела модели (1/τ для
+// непродлевающейся, 1/(e·τ) для продлевающейся), возвращается ошибка и Data не меняется.
+// Применённая поправка запоминается в DeadTime/DeadTimeModel; повторное применение и
+// применение после SubtractBackground — ошибка.
+func (lp *LicelProfile) CorrectDeadTime(tau float64, model DeadTimeModel) error {
+	apply, err := lp.deadTimeCorrection(tau, model)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// deadTimeCorrection — проверяет, что поправку CorrectDeadTime можно применить,
+// и возвращает функцию, которая её применяет
+func (lp *LicelProfile) deadTimeCorrection(tau float64, model DeadTimeModel) (func(), error) {
+	if !lp.Photon {
+		return nil, fmt.Errorf("CorrectDeadTime: %s channel is not photon counting", lp.DeviceID)
+	}
+	if lp.DeadTimeModel != DeadTimeNone {
+		return nil, fmt.Errorf("CorrectDeadTime: already corrected (%s, %g ns)", lp.DeadTimeModel, lp.DeadTime)
+	}
+	if lp.Background.Method != BackgroundNone {
+		return nil, fmt.Errorf("CorrectDeadTime: background already subtracted; correct dead time first")
+	}
+	if tau <= 0 || math.IsNaN(tau) || math.IsInf(tau, 0) {
+		return nil, fmt.Errorf("CorrectDeadTime: dead time must be positive, got %g ns", tau)
+	}
+	tauUs := tau / 1000 // Data в МГц = 1/мкс
+
+	var limit float64
+	switch model {
+	case DeadTimeNonParalyzable:
+		limit = 1 / tauUs
+	case DeadTimeParalyzable:
+		limit = 1 / (math.E * tauUs)
+	default:
+		return nil, fmt.Errorf("CorrectDeadTime: unknown model %s", model)
+	}
+	for i, m := range lp.Data {
+		if m >= limit {
+			return nil, fmt.Errorf("CorrectDeadTime: bin %d: %g MHz reaches the %s limit %g MHz for %g ns", i, m, model, limit, tau)
+		}
+	}
+
+	return func() {
+		for i, m := range lp.Data {
+			if !(m > 0) {
+				continue
+			}
+			if model == DeadTimeNonParalyzable {
+				lp.Data[i] = m / (1 - m*tauUs)
+			} else {
+				lp.Data[i] = paralyzableRate(m*tauUs) / tauUs
+			}
+		}
+		lp.DeadTime, lp.DeadTimeModel = tau, model
+	}, nil
+}
+
+// paralyzableRate — решение x·exp(−x) = y на ветви x ∈ [0, 1] для 0 < y < 1/e.
+// Функция вогнута и возрастает, поэтому метод Ньютона из x = y сходится монотонно.
+func paralyzableRate(y float64) float64 {
+	x := y
+	for range paralyzableIterations {
+		e := math.Exp(-x)
+		dx := (x*e - y) / ((1 - x) * e)
+		x -= dx
+		if math.Abs(dx) <= 1e-15*x {
+			break
+		}
+	}
+	return x
+}
+
+// CorrectDeadTime — поправка на мёртвое время фотонных профилей файла (см. LicelProfile.CorrectDeadTime).
+// Для профиля берётся первое подходящее значение из deadTimes; аналоговые профили
+// и фотонные без подходящего значения не изменяются. Если поправку нельзя применить
+// хотя бы к одному профилю, возвращается ошибка и файл не меняется.
+func (lf *LicelFile) CorrectDeadTime(deadTimes ...DeadTime) error {
+	updates, err := lf.deadTimeCorrections(deadTimes)
+	if err != nil {
+		return err
+	}
+	applyUpdates(updates)
+	return nil
+}
+
+// deadTimeCorrections — проверенные поправки на мёртвое время профилей файла
+func (lf *LicelFile) deadTimeCorrections(deadTimes []DeadTime) ([]func(), error) {
+	var updates []func()
+	for i := range lf.Profiles {
+		pr := &lf.Profiles[i]
+		if !pr.Photon {
+			continue
+		}
+		for _, dt := range deadTimes {
+			if !dt.Match(pr) {
+				continue
+			}
+			apply, err := pr.deadTimeCorrection(dt.Tau, dt.Model)
+			if err != nil {
+				return nil, fmt.Errorf("profile %d: %w", i, err)
+			}
+			updates = append(updates, apply)
+			break
+		}
+	}
+	return updates, nil
+}
+
+// CorrectDeadTime — поправка на мёртвое время во всех файлах пака с одними и теми же deadTimes.
+// При ошибке в любом файле пак не изменяется.
+func (lp *LicelPack) CorrectDeadTime(deadTimes ...DeadTime) error {
+	return lp.updateFiles(func(_ string, lf *LicelFile) ([]func(), error) {
+		return lf.deadTimeCorrections(deadTimes)
+	})
+}
@@ -0,0 +1,146 @@
+package licelformat
+
+import (
+	"encoding/json"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadTimeModel_String(t *testing.T) {
+	assert.Equal(t, "none", DeadTimeNone.String())
+	assert.Equal(t, "non-paralyzable", DeadTimeNonParalyzable.String())
+	assert.Equal(t, "paralyzable", DeadTimeParalyzable.String())
+	assert.Equal(t, "DeadTimeModel(7)", DeadTimeModel(7).String())
+}
+
+func TestLicelFile_JSON_RoundTrip_Corrections(t *testing.T) {
+	pr := backgroundTestProfile()
+	pr.Photon, pr.DeviceID, pr.NShots = true, DeviceIDPhoton, 10
+	require.NoError(t, pr.CorrectDeadTime(2, DeadTimeParalyzable))
+	require.NoError(t, pr.SubtractBackground(BackgroundWindow{Method: BackgroundMedian, From: 50}))
+	lf := LicelFile{MeasurementSite: "Site", NDatasets: 1, Profiles: LicelProfilesList{pr}}
+
+	b, err := json.Marshal(lf)
+	require.NoError(t, err)
+	var got LicelFile
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Len(t, got.Profiles, 1)
+	assert.Equal(t, DeadTimeParalyzable, got.Profiles[0].DeadTimeModel)
+	assert.Equal(t, 2.0, got.Profiles[0].DeadTime)
+	assert.Equal(t, pr.Background, got.Profiles[0].Background)
+	assert.Equal(t, pr.Data, got.Profiles[0].Data)
+
+	var m DeadTimeModel
+	assert.Error(t, m.UnmarshalText([]byte("bogus")))
+	var bm BackgroundMethod
+	assert.Error(t, bm.UnmarshalText([]byte("bogus")))
+}
+
+func TestLicelProfile_CorrectDeadTime_NonParalyzable(t *testing.T) {
+	// τ = 4 нс: 100 МГц → 100/(1 − 0.4) МГц
+	pr := LicelProfile{Photon: true, Data: []float64{0, 10, 100, -1}}
+	require.NoError(t, pr.CorrectDeadTime(4, DeadTimeNonParalyzable))
+	assert.InDeltaSlice(t, []float64{0, 10 / 0.96, 100 / 0.6, -1}, pr.Data, 1e-9)
+	assert.Equal(t, 4.0, pr.DeadTime)
+	assert.Equal(t, DeadTimeNonParalyzable, pr.DeadTimeModel)
+
+	err := pr.CorrectDeadTime(4, DeadTimeNonParalyzable)
+	assert.ErrorContains(t, err, "already corrected")
+}
+
+func TestLicelProfile_CorrectDeadTime_Paralyzable(t *testing.T) {
+	tau := 3.0 // нс
+	truth := []float64{1, 50, 200, 333}
+	measured := make([]float64, len(truth))
+	for i, n := range truth {
+		measured[i] = n * math.Exp(-n*tau/1000)
+	}
+	pr := LicelProfile{Photon: true, Data: measured}
+	require.NoError(t, pr.CorrectDeadTime(tau, DeadTimeParalyzable))
+	assert.InDeltaSlice(t, truth, pr.Data, 1e-6)
+	assert.Equal(t, DeadTimeParalyzable, pr.DeadTimeModel)
+}
+
+func TestLicelProfile_CorrectDeadTime_Errors(t *testing.T) {
+	analog := LicelProfile{DeviceID: DeviceIDAnalog, Data: []float64{1}}
+	assert.Error(t, analog.CorrectDeadTime(4, DeadTimeNonParalyzable))
+
+	pr := LicelProfile{Photon: true, Data: []float64{1, 250}}
+	assert.Error(t, pr.CorrectDeadTime(0, DeadTimeNonParalyzable))
+	assert.Error(t, pr.CorrectDeadTime(4, DeadTimeNone))
+	// 250 МГц при 4 нс — предел непродлевающейся модели; 1/(e·4 нс) ≈ 92 МГц — продлевающейся
+	assert.Error(t, pr.CorrectDeadTime(4, DeadTimeNonParalyzable))
+	assert.Error(t, pr.CorrectDeadTime(4, DeadTimeParalyzable))
+	assert.Equal(t, []float64{1, 250}, pr.Data)
+	assert.Equal(t, DeadTimeNone, pr.DeadTimeModel)
+}
+
+func TestLicelPack_CorrectDeadTime(t *testing.T) {
+	pack := testPack(
+		LicelProfile{Wavelength: 355, DeviceID: DeviceIDAnalog, Data: []float64{100}},
+		LicelProfile{Wavelength: 355, DeviceID: DeviceIDPhoton, Photon: true, Data: []float64{100}},
+		LicelProfile{Wavelength: 532, DeviceID: DeviceIDPhoton, Photon: true, Data: []float64{100}},
+	)
+	err := pack.CorrectDeadTime(
+		DeadTime{Channel: Channel{Wavelength: 355}, Tau: 4, Model: DeadTimeNonParalyzable},
+	)
+	require.NoError(t, err)
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, 100.0, lf.Profiles[0].Data[0])
+		assert.InDelta(t, 100/0.6, lf.Profiles[1].Data[0], 1e-9)
+		assert.Equal(t, 100.0, lf.Profiles[2].Data[0])
+		assert.Equal(t, DeadTimeNone, lf.Profiles[2].DeadTimeModel)
+	})
+}
+
+func TestLicelPack_CorrectDeadTime_Atomic(t *testing.T) {
+	pack := testPack(
+		LicelProfile{Wavelength: 355, DeviceID: DeviceIDPhoton, Photon: true, Data: []float64{100}},
+		LicelProfile{Wavelength: 532, DeviceID: DeviceIDPhoton, Photon: true, Data: []float64{100}},
+	)
+	lf := pack.Data["/data/b"]
+	lf.Profiles[1].Data[0] = 300
+	dt := DeadTime{Tau: 4, Model: DeadTimeNonParalyzable} // предел 250 МГц
+
+	// профиль 532 нм файла b превышает предел: не меняется ни один профиль пака
+	err := pack.CorrectDeadTime(dt)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/data/b: profile 1")
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, 100.0, lf.Profiles[0].Data[0])
+		assert.Equal(t, DeadTimeNone, lf.Profiles[0].DeadTimeModel)
+	})
+
+	require.Error(t, lf.CorrectDeadTime(dt))
+	assert.Equal(t, DeadTimeNone, lf.Profiles[0].DeadTimeModel)
+
+	// после ошибки поправку можно повторить с подходящими параметрами
+	require.NoError(t, pack.CorrectDeadTime(DeadTime{Tau: 2, Model: DeadTimeNonParalyzable}))
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.InDelta(t, 100/0.8, lf.Profiles[0].Data[0], 1e-9)
+	})
+}
+
+func TestLicelProfile_DeadTime_Exported(t *testing.T) {
+	pr := LicelProfile{Photon: true, NShots: 10, BinWidth: 7.5, Polarization: "o", DeviceID: DeviceIDPhoton, NDataPoints: 1, Data: []float64{10}}
+	require.NoError(t, pr.CorrectDeadTime(4, DeadTimeParalyzable))
+
+	b, err := json.Marshal(pr)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"dead_time":4,"dead_time_model":"paralyzable"`)
+
+	pack := &LicelPack{Data: map[string]LicelFile{
+		"/data/a": {MeasurementSite: "Site", NDatasets: 1, Profiles: LicelProfilesList{pr}},
+	}}
+	fname := filepath.Join(t.TempDir(), "deadtime.nc")
+	require.NoError(t, pack.SaveToNetCDF3(fname))
+	loaded, err := LoadLicelPackFromNetCDF3(fname)
+	require.NoError(t, err)
+	got := loaded.Data["/data/a"].Profiles[0]
+	assert.Equal(t, 4.0, got.DeadTime)
+	assert.Equal(t, DeadTimeParalyzable, got.DeadTimeModel)
+}
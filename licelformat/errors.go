@@ -0,0 +1,116 @@
+package licelformat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Категории ошибок разбора; проверяются через errors.Is
+var (
+	ErrTruncatedData = errors.New("truncated data")         // файл кончился раньше, чем описано в заголовке
+	ErrBadHeader     = errors.New("bad header")             // строка заголовка не разбирается
+	ErrDatasetCount  = errors.New("dataset count mismatch") // число заголовков профилей не совпадает с NDatasets
+	ErrLimitExceeded = errors.New("limit exceeded")         // значение из заголовка превышает Limits
+)
+
+// ParseError — ошибка разбора LICEL-файла с указанием места.
+// Kind — одна из категорий ErrTruncatedData, ErrBadHeader, ErrDatasetCount, ErrLimitExceeded
+// (nil для ошибок ввода-вывода), Err — исходная причина (например, *strconv.NumError).
+// Обе доступны через errors.Is/As.
+type ParseError struct {
+	Kind    error  // категория ошибки
+	Line    int    // номер строки заголовка, начиная с 1; 0 — бинарные данные
+	Offset  int64  // смещение от начала файла: начало строки или блока данных, байт
+	Field   string // имя поля, например "bin width"; "" — ошибка не в отдельном поле
+	Profile int    // индекс профиля; -1 — ошибка не относится к профилю
+	Token   string // исходное значение поля
+	Err     error  // причина
+}
+
+// Error — описание ошибки с местом в файле
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d, ", e.Line)
+	}
+	if e.Profile >= 0 {
+		fmt.Fprintf(&b, "profile %d, ", e.Profile)
+	}
+	fmt.Fprintf(&b, "offset %d", e.Offset)
+	if e.Kind != nil {
+		fmt.Fprintf(&b, ": %v", e.Kind)
+	}
+	switch {
+	case e.Field != "" && e.Token != "":
+		fmt.Fprintf(&b, ": parsing %s %q", e.Field, e.Token)
+	case e.Field != "":
+		fmt.Fprintf(&b, ": reading %s", e.Field)
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&b, ": %v", e.Err)
+	}
+	return b.String()
+}
+
+// Unwrap — категория и причина ошибки для errors.Is/As
+func (e *ParseError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// fieldError — ошибка разбора поля заголовка; место заполняется вызывающим через atPosition
+func fieldError(field, token string, err error) *ParseError {
+	return &ParseError{Kind: ErrBadHeader, Field: field, Token: token, Profile: -1, Err: err}
+}
+
+// headerError — ошибка строки заголовка без привязки к полю
+func headerError(format string, args ...any) *ParseError {
+	return &ParseError{Kind: ErrBadHeader, Profile: -1, Err: fmt.Errorf(format, args...)}
+}
+
+// readError — ошибка чтения; конец файла относится к ErrTruncatedData
+func readError(line int, offset int64, profile int, err error) *ParseError {
+	pe := &ParseError{Line: line, Offset: offset, Profile: profile, Err: err}
+	switch {
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		pe.Kind = ErrTruncatedData
+	case errors.Is(err, errLineTooLong):
+		pe.Kind = ErrLimitExceeded
+	}
+	return pe
+}
+
+// limitError — значение поля заголовка превышает ограничение из Limits;
+// место заполняется вызывающим через atPosition
+func limitError(field string, value, max int64) *ParseError {
+	return &ParseError{Kind: ErrLimitExceeded, Field: field, Token: strconv.FormatInt(value, 10), Profile: -1,
+		Err: fmt.Errorf("exceeds limit %d", max)}
+}
+
+// dataError — ошибка чтения бинарных данных профиля
+func dataError(offset int64, profile int, err error) *ParseError {
+	pe := readError(0, offset, profile, err)
+	pe.Field = "data"
+	return pe
+}
+
+// atPosition — дополняет ParseError номером строки, смещением и индексом профиля
+func atPosition(err error, line int, offset int64, profile int) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Line, pe.Offset = line, offset
+		if pe.Profile < 0 {
+			pe.Profile = profile
+		}
+	}
+	return err
+}
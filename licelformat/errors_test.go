@@ -0,0 +1,158 @@
+package licelformat
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- ParseError ---
+
+func TestParseError_Error(t *testing.T) {
+	err := &ParseError{Kind: ErrBadHeader, Line: 4, Offset: 265, Field: "bin width", Profile: 0, Token: "x", Err: errors.New("invalid syntax")}
+	assert.Equal(t, `line 4, profile 0, offset 265: bad header: parsing bin width "x": invalid syntax`, err.Error())
+
+	err = &ParseError{Kind: ErrTruncatedData, Offset: 1000, Field: "data", Profile: 3, Err: io.ErrUnexpectedEOF}
+	assert.Equal(t, "profile 3, offset 1000: truncated data: reading data: unexpected EOF", err.Error())
+
+	err = &ParseError{Line: 2, Offset: 19, Profile: -1, Err: errors.New("disk failure")}
+	assert.Equal(t, "line 2, offset 19: disk failure", err.Error())
+}
+
+func TestParseError_BadProfileField(t *testing.T) {
+	raw := bytes.Replace(lenientTestBytes("02"), []byte(" 3.75 00355.o"), []byte(" 3,75 00355.o"), 1)
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.Error(t, err)
+
+	assert.ErrorIs(t, err, ErrBadHeader)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, 5, pe.Line)
+	assert.Equal(t, 1, pe.Profile)
+	assert.Equal(t, "bin width", pe.Field)
+	assert.Equal(t, "3,75", pe.Token)
+	lineStart := bytes.Index(raw, []byte(" 1 1 1 00003 1 0850 3,75"))
+	assert.Equal(t, int64(lineStart), pe.Offset)
+
+	var numErr *strconv.NumError
+	assert.ErrorAs(t, err, &numErr)
+}
+
+func TestParseError_BadSecondLine(t *testing.T) {
+	raw := bytes.Replace(lenientTestBytes("02"), []byte(" 0020 0131.9"), []byte(" high 0131.9"), 1)
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, ErrBadHeader)
+	assert.Equal(t, 2, pe.Line)
+	assert.Equal(t, -1, pe.Profile)
+	assert.Equal(t, "altitude", pe.Field)
+	assert.Equal(t, "high", pe.Token)
+	assert.Equal(t, int64(len(" x2020041.120000\r\n")), pe.Offset)
+}
+
+func TestParseError_TruncatedData(t *testing.T) {
+	full := lenientTestBytes("02")
+	raw := full[:len(full)-2-6]
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+
+	assert.ErrorIs(t, err, ErrTruncatedData)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, 1, pe.Profile)
+	assert.Equal(t, 0, pe.Line)
+	assert.Equal(t, "data", pe.Field)
+	assert.Equal(t, int64(len(full)-2-12), pe.Offset)
+}
+
+func TestParseError_TruncatedHeader(t *testing.T) {
+	full := lenientTestBytes("02")
+	raw := full[:bytes.Index(full, []byte(" 0000600"))+5]
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+
+	assert.ErrorIs(t, err, ErrTruncatedData)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, 3, pe.Line)
+}
+
+func TestParseError_DatasetCount(t *testing.T) {
+	for _, datasets := range []string{"01", "03", "-1"} {
+		_, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes(datasets)))
+		assert.ErrorIs(t, err, ErrDatasetCount, "NDatasets %s", datasets)
+	}
+}
+
+func TestParseError_NegativeDataPoints(t *testing.T) {
+	_, err := newLicelProfile(" 1 1 1 -0003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0")
+	assert.ErrorIs(t, err, ErrBadHeader)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, "data points", pe.Field)
+}
+
+func TestParseError_ZeroShots(t *testing.T) {
+	_, err := newLicelProfile(" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000000 3.1746 BC0")
+	assert.ErrorIs(t, err, ErrBadHeader)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, "n shots", pe.Field)
+
+	// масштаб отсчётов не определён
+	for line, field := range map[string]string{
+		" 1 0 1 00003 1 0850 3.75 00532.p 0 0 00 000 12 000600 0 BT0":     "discr level",
+		" 1 0 1 00003 1 0850 3.75 00532.p 0 0 00 000 12 000600 Inf BT0":   "discr level",
+		" 1 0 1 00003 1 0850 3.75 00532.p 0 0 00 000 9999 000600 1 BT0":   "discr level",
+		" 1 1 1 00003 1 0850 Inf 00532.p 0 0 00 000 00 000600 3.1746 BC0": "bin width",
+	} {
+		_, err := newLicelProfile(line)
+		require.ErrorIs(t, err, ErrBadHeader, line)
+		require.ErrorAs(t, err, &pe)
+		assert.Equal(t, field, pe.Field, line)
+	}
+	_, err = newLicelProfile(" 1 0 1 00003 1 0850 3.75 00532.p 0 0 00 000 -4 000600 1 BT0")
+	assert.NoError(t, err, "negative adc bits give a finite scale")
+
+	// профиль без данных с 0 импульсов допустим и записывается
+	pr, err := newLicelProfile(" 1 1 1 00000 1 0850 3.75 00532.p 0 0 00 000 00 000000 3.1746 BC0")
+	require.NoError(t, err)
+	b, err := pr.profileRaw()
+	require.NoError(t, err)
+	assert.Empty(t, b)
+}
+
+func TestParseError_ThroughPack(t *testing.T) {
+	dir := t.TempDir()
+	full := lenientTestBytes("02")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.dat"), full[:len(full)-6], 0o644))
+
+	_, err := NewLicelPack(filepath.Join(dir, "*.dat"))
+	assert.ErrorIs(t, err, ErrTruncatedData)
+	assert.True(t, strings.Contains(err.Error(), "b.dat"))
+}
+
+func TestParseError_LicelReader(t *testing.T) {
+	full := lenientTestBytes("02")
+	lr, err := NewLicelReader(bytes.NewReader(full[:len(full)-6]))
+	require.NoError(t, err)
+
+	_, err = lr.ReadProfile(1)
+	assert.ErrorIs(t, err, ErrTruncatedData)
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, 1, pe.Profile)
+
+	_, _, err = lr.SelectProfile(true, 355, "o")
+	require.ErrorAs(t, err, &pe)
+	assert.Equal(t, 1, pe.Profile)
+}
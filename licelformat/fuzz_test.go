@@ -0,0 +1,82 @@
+package licelformat
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fuzzLimits — ограничения, при которых один вход не может занять больше нескольких мегабайт
+var fuzzLimits = Limits{MaxDatasets: 64, MaxDataPoints: 1 << 16, MaxFileSize: 1 << 22}
+
+// fuzzSeed — файл из testdata, которым засеваются fuzz-тесты
+func fuzzSeed(f *testing.F) []byte {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	return raw
+}
+
+// Запуск: go test -fuzz FuzzLoadLicelFileFromReader -fuzzminimizetime 1s ./licelformat
+// (минимизация входов размером с файл из testdata по умолчанию занимает минуты)
+func FuzzLoadLicelFileFromReader(f *testing.F) {
+	raw := fuzzSeed(f)
+	f.Add(raw)
+	f.Add(raw[:len(raw)/2])
+	f.Add(lenientTestBytes("02"))
+	f.Add(lenientTestBytes("03"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, opts := range [][]LoadOption{
+			{WithLimits(fuzzLimits)},
+			{WithLimits(fuzzLimits), WithLenient()},
+			{WithLimits(fuzzLimits), WithHeaderOnly()},
+		} {
+			lf, err := LoadLicelFileFromReader(bytes.NewReader(data), opts...)
+			if err != nil {
+				continue
+			}
+			if lf.NDatasets != len(lf.Profiles) {
+				t.Fatalf("NDatasets %d, profiles %d", lf.NDatasets, len(lf.Profiles))
+			}
+			if lf.HeaderOnly {
+				continue
+			}
+			for i, pr := range lf.Profiles {
+				if len(pr.Data) != pr.NDataPoints {
+					t.Fatalf("profile %d: NDataPoints %d, data %d", i, pr.NDataPoints, len(pr.Data))
+				}
+			}
+			if err := lf.WriteTo(io.Discard, ""); err != nil {
+				t.Fatalf("writing loaded file: %v", err)
+			}
+		}
+	})
+}
+
+func FuzzNewLicelProfile(f *testing.F) {
+	raw := fuzzSeed(f)
+	lines := strings.Split(string(raw[:bytes.Index(raw, []byte("\n\r\n"))]), "\n")
+	for _, line := range lines[3:] {
+		f.Add(line + "\n")
+	}
+	f.Add(" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0 extra\r\n")
+
+	f.Fuzz(func(t *testing.T, line string) {
+		pr, err := newLicelProfile(line)
+		if err != nil {
+			return
+		}
+		if pr.NDataPoints < 0 {
+			t.Fatalf("negative NDataPoints %d", pr.NDataPoints)
+		}
+		if len(pr.DeviceID) != 2 {
+			t.Fatalf("device ID %q", pr.DeviceID)
+		}
+		_ = pr.metadata()
+	})
+}
@@ -0,0 +1,422 @@
+package licelformat
+
+import (
+	"fmt"
+	"math"
+)
+
+// GlueMethod — способ расчёта коэффициентов склейки analog ≈ K·photon + Offset
+type GlueMethod int
+
+const (
+	GlueRatio      GlueMethod = iota // K — среднее отношение analog/photon в окне, Offset = 0
+	GlueRegression                   // K и Offset — прямая analog = K·photon + Offset по МНК
+)
+
+// String — название способа
+func (m GlueMethod) String() string {
+	switch m {
+	case GlueRatio:
+		return "ratio"
+	case GlueRegression:
+		return "regression"
+	}
+	return fmt.Sprintf("GlueMethod(%d)", int(m))
+}
+
+// MarshalText — способ в JSON выводится названием
+func (m GlueMethod) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText — способ по названию из MarshalText
+func (m *GlueMethod) UnmarshalText(text []byte) error {
+	for v := GlueRatio; v <= GlueRegression; v++ {
+		if v.String() == string(text) {
+			*m = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown glue method %q", text)
+}
+
+// GlueBlend — способ смешивания каналов в окне склейки: вес аналогового канала w
+// меняется от 1 у начала окна до 0 у конца (кроме BlendMean и BlendSNR)
+type GlueBlend int
+
+const (
+	BlendMean    GlueBlend = iota // w = 0.5 во всём окне, скачки на его краях
+	BlendLinear                   // линейный спад w
+	BlendCosine                   // w = 0.5·(1 + cos(π·t)), t — доля окна
+	BlendSigmoid                  // логистическая ступень с центром в середине окна
+	BlendSNR                      // w обратно пропорционален дисперсии канала в бине
+)
+
+// glueSigmoidSteepness — крутизна BlendSigmoid: аргумент логистической функции меняется
+// от −5 до 5 по окну, затем веса нормируются к 1 и 0 на его краях
+const glueSigmoidSteepness = 10.0
+
+// String — название способа
+func (b GlueBlend) String() string {
+	switch b {
+	case BlendMean:
+		return "mean"
+	case BlendLinear:
+		return "linear"
+	case BlendCosine:
+		return "cosine"
+	case BlendSigmoid:
+		return "sigmoid"
+	case BlendSNR:
+		return "snr"
+	}
+	return fmt.Sprintf("GlueBlend(%d)", int(b))
+}
+
+// MarshalText — способ в JSON выводится названием
+func (b GlueBlend) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText — способ по названию из MarshalText
+func (b *GlueBlend) UnmarshalText(text []byte) error {
+	for v := BlendMean; v <= BlendSNR; v++ {
+		if v.String() == string(text) {
+			*b = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown glue blend %q", text)
+}
+
+// Параметры автоматического выбора окна склейки по умолчанию
+const (
+	DefaultGlueMaxPhotonRate = 10.0 // верхняя граница линейного режима счёта, МГц
+	DefaultGlueMinSNR        = 5.0  // минимальное отношение аналогового сигнала к шуму
+	DefaultGlueMinBins       = 10   // минимальная длина окна, бины
+)
+
+// GlueOptions — параметры склейки для GlueWith. Нулевые MaxPhotonRate, MinSNR и MinBins
+// означают значения по умолчанию.
+type GlueOptions struct {
+	Method GlueMethod
+	Blend  GlueBlend // смешивание каналов в окне; по умолчанию BlendMean
+	From   float64   // начало окна склейки, м; с AutoWindow — нижняя граница поиска
+	To     float64   // конец окна склейки включительно, м; с AutoWindow — верхняя граница поиска, 0 — до конца профиля
+
+	// AutoWindow — выбрать окно автоматически: самый длинный участок в [From, To], где скорость
+	// счёта фотонов в (0, MaxPhotonRate] МГц, а аналоговый сигнал превышает фон на MinSNR его СКО.
+	// Фон и СКО берутся из Background аналогового канала, если фон вычтен, иначе по последним
+	// 10 % бинов.
+	AutoWindow    bool
+	MaxPhotonRate float64
+	MinSNR        float64
+	MinBins       int
+}
+
+// withDefaults — копия с подставленными значениями по умолчанию
+func (o GlueOptions) withDefaults() GlueOptions {
+	if o.MaxPhotonRate == 0 {
+		o.MaxPhotonRate = DefaultGlueMaxPhotonRate
+	}
+	if o.MinSNR == 0 {
+		o.MinSNR = DefaultGlueMinSNR
+	}
+	if o.MinBins == 0 {
+		o.MinBins = DefaultGlueMinBins
+	}
+	return o
+}
+
+// GlueResult — коэффициенты склейки, окно и качество согласования каналов в нём
+type GlueResult struct {
+	Method   GlueMethod `json:"method"`
+	Blend    GlueBlend  `json:"blend"`
+	K        float64    `json:"k"`        // множитель фотонного канала
+	Offset   float64    `json:"offset"`   // смещение, единицы аналогового канала
+	First    int        `json:"first"`    // индекс первого бина окна
+	Last     int        `json:"last"`     // индекс последнего бина окна (включительно)
+	From     float64    `json:"from"`     // дальность первого бина окна, м
+	To       float64    `json:"to"`       // дальность последнего бина окна, м
+	Bins     int        `json:"bins"`     // число бинов окна, по которым рассчитаны K и Offset
+	R2       float64    `json:"r2"`       // коэффициент детерминации analog по K·photon + Offset
+	Residual float64    `json:"residual"` // СКО остатков analog − (K·photon + Offset)
+
+	// Weights — вес аналогового канала в каждом бине склеенного профиля:
+	// glued[i] = Weights[i]·analog[i] + (1 − Weights[i])·(K·photon[i] + Offset)
+	Weights []float64 `json:"weights,omitempty"`
+}
+
+// GlueWith — склеивает аналоговый и фотонный каналы длины волны wvl с параметрами opts
+// и возвращает склеенный профиль (DeviceID="BG") вместе с коэффициентами, окном и качеством склейки.
+// Ниже окна берётся аналоговый канал, в окне — w·analog + (1 − w)·(K·photon + Offset)
+// с весом w по opts.Blend, выше окна — K·photon + Offset. Каналы сопоставляются по индексу бина, поэтому их нулевые
+// дальности должны совпадать (см. AlignZeroBins).
+func (lf *LicelFile) GlueWith(wvl float64, polarization string, opts GlueOptions) (LicelProfile, GlueResult, error) {
+	analog, ok := lf.SelectProfile(false, wvl, polarization)
+	if !ok {
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: analog channel not found for wavelength %.0f", wvl)
+	}
+	photon, ok := lf.SelectProfile(true, wvl, polarization)
+	if !ok {
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: photon channel not found for wavelength %.0f", wvl)
+	}
+
+	if analog.BinWidth <= 0 {
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: analog channel has invalid bin width %.2f", analog.BinWidth)
+	}
+	if photon.BinWidth <= 0 {
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: photon channel has invalid bin width %.2f", photon.BinWidth)
+	}
+
+	dataLen := min(len(analog.Data), len(photon.Data))
+	o := opts.withDefaults()
+	var idx1, idx2 int
+	var err error
+	if o.AutoWindow {
+		idx1, idx2, err = glueAutoWindow(&analog, &photon, dataLen, &o)
+	} else {
+		idx1, idx2, err = glueWindow(&analog, dataLen, &o)
+	}
+	if err != nil {
+		return LicelProfile{}, GlueResult{}, err
+	}
+
+	res, err := glueFit(analog.Data, photon.Data, idx1, idx2, o.Method)
+	if err != nil {
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: window [%d, %d]: %w", idx1, idx2, err)
+	}
+	zero := analog.ZeroBin()
+	res.First, res.Last = idx1, idx2
+	res.From = (float64(idx1) - zero) * analog.BinWidth
+	res.To = (float64(idx2) - zero) * analog.BinWidth
+	res.Blend = o.Blend
+	if res.Weights, err = glueWeights(&analog, &photon, dataLen, res, o.Blend); err != nil {
+		return LicelProfile{}, GlueResult{}, err
+	}
+
+	// Создаём результирующий профиль
+	result := LicelProfile{
+		Active:       true,
+		Photon:       false,
+		LaserType:    analog.LaserType,
+		NDataPoints:  dataLen,
+		Reserved:     analog.Reserved,
+		HighVoltage:  analog.HighVoltage,
+		BinWidth:     analog.BinWidth,
+		Wavelength:   analog.Wavelength,
+		Polarization: analog.Polarization,
+		BinShift:     analog.BinShift,
+		DecBinShift:  analog.DecBinShift,
+		AdcBits:      analog.AdcBits,
+		NShots:       analog.NShots,
+		DiscrLevel:   analog.DiscrLevel,
+		DeviceID:     DeviceIDGlued,
+		NCrate:       analog.NCrate,
+		Data:         make([]float64, dataLen),
+	}
+
+	for i, w := range res.Weights {
+		scaled := res.K*photon.Data[i] + res.Offset
+		switch w {
+		case 1:
+			result.Data[i] = analog.Data[i]
+		case 0:
+			result.Data[i] = scaled
+		default:
+			result.Data[i] = w*analog.Data[i] + (1-w)*scaled
+		}
+	}
+
+	return result, res, nil
+}
+
+// glueWindow — индексы заданного окна [From, To] по RangeIndex аналогового канала
+func glueWindow(analog *LicelProfile, dataLen int, o *GlueOptions) (int, int, error) {
+	if o.From >= o.To {
+		return 0, 0, fmt.Errorf("glue: window start %.2f m must be less than its end %.2f m", o.From, o.To)
+	}
+	idx1 := analog.RangeIndex(o.From)
+	idx2 := analog.RangeIndex(o.To)
+	if idx1 < 0 || idx1 >= dataLen {
+		return 0, 0, fmt.Errorf("glue: h1 (%.2f m) maps to index %d, out of range [0, %d)", o.From, idx1, dataLen)
+	}
+	if idx2 >= dataLen {
+		return 0, 0, fmt.Errorf("glue: h2 (%.2f m) maps to index %d, exceeds data length %d", o.To, idx2, dataLen)
+	}
+	return idx1, idx2, nil
+}
+
+// glueAutoWindow — самый длинный участок бинов в границах поиска, где фотонный канал
+// в линейном режиме, а аналоговый — выше шума (см. GlueOptions.AutoWindow)
+func glueAutoWindow(analog, photon *LicelProfile, dataLen int, o *GlueOptions) (int, int, error) {
+	if dataLen == 0 {
+		return 0, 0, fmt.Errorf("glue: channels have no data")
+	}
+	lo := max(analog.RangeIndex(o.From), int(math.Ceil(analog.ZeroBin())), 0)
+	hi := dataLen - 1
+	if o.To > 0 {
+		hi = min(analog.RangeIndex(o.To), hi)
+	}
+
+	level, noise := analogNoise(analog, dataLen)
+	threshold := level + o.MinSNR*noise
+
+	best1, best2 := 0, -1
+	start := -1
+	for i := lo; i <= hi+1; i++ {
+		good := i <= hi && photon.Data[i] > 0 && photon.Data[i] <= o.MaxPhotonRate && analog.Data[i] >= threshold
+		switch {
+		case good && start < 0:
+			start = i
+		case !good && start >= 0:
+			if i-1-start > best2-best1 {
+				best1, best2 = start, i-1
+			}
+			start = -1
+		}
+	}
+	if best2-best1+1 < o.MinBins {
+		return 0, 0, fmt.Errorf("glue: no window of %d bins with photon rate in (0, %g] MHz and analog signal above %g (SNR %g) in bins [%d, %d]",
+			o.MinBins, o.MaxPhotonRate, threshold, o.MinSNR, lo, hi)
+	}
+	return best1, best2, nil
+}
+
+// analogNoise — уровень и СКО шума аналогового канала: из Background, если фон вычтен,
+// иначе по последним 10 % из первых dataLen бинов
+func analogNoise(analog *LicelProfile, dataLen int) (level, std float64) {
+	if analog.Background.Method != BackgroundNone {
+		return 0, analog.Background.Std
+	}
+	tail := analog.Data[max(dataLen-max(dataLen/10, 2), 0):dataLen]
+	level = mean(tail)
+	return level, stdDev(tail, level)
+}
+
+// glueWeights — вес аналогового канала в каждом бине: 1 до окна res.First..res.Last,
+// 0 после него, в окне — по способу blend
+func glueWeights(analog, photon *LicelProfile, dataLen int, res GlueResult, blend GlueBlend) ([]float64, error) {
+	w := make([]float64, dataLen)
+	for i := range res.First {
+		w[i] = 1
+	}
+	span := float64(res.Last - res.First)
+	g0, g1 := sigmoid(-glueSigmoidSteepness/2), sigmoid(glueSigmoidSteepness/2)
+
+	var analogVar, countTime float64
+	if blend == BlendSNR {
+		if photon.NShots <= 0 {
+			return nil, fmt.Errorf("glue: snr blending needs positive n shots of the photon channel, got %d", photon.NShots)
+		}
+		_, std := analogNoise(analog, dataLen)
+		analogVar = std * std
+		countTime = float64(photon.NShots) * photon.binTime()
+	}
+
+	for i := res.First; i <= res.Last; i++ {
+		t := 0.5
+		if span > 0 {
+			t = float64(i-res.First) / span
+		}
+		switch blend {
+		case BlendMean:
+			w[i] = 0.5
+		case BlendLinear:
+			w[i] = 1 - t
+		case BlendCosine:
+			w[i] = 0.5 * (1 + math.Cos(math.Pi*t))
+		case BlendSigmoid:
+			w[i] = 1 - (sigmoid(glueSigmoidSteepness*(t-0.5))-g0)/(g1-g0)
+		case BlendSNR:
+			// скорость счёта пуассоновская: дисперсия rate/(NShots·время бина),
+			// вычтенный фон возвращается, так как он тоже вносит шум
+			rate := photon.Data[i]
+			if photon.Background.Method != BackgroundNone {
+				rate += photon.Background.At((float64(i) - photon.ZeroBin()) * photon.BinWidth)
+			}
+			photonVar := res.K * res.K * max(rate, 0) / countTime
+			if analogVar+photonVar > 0 {
+				w[i] = photonVar / (analogVar + photonVar)
+			} else {
+				w[i] = 0.5
+			}
+		default:
+			return nil, fmt.Errorf("glue: unknown blend %s", blend)
+		}
+	}
+	return w, nil
+}
+
+// sigmoid — логистическая функция 1/(1 + e^−x)
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// glueFit — коэффициенты склейки методом method по бинам [idx1, idx2] и качество согласования.
+// Бины с NaN пропускаются; для GlueRatio пропускаются и бины с нулевым фотонным сигналом.
+func glueFit(analog, photon []float64, idx1, idx2 int, method GlueMethod) (GlueResult, error) {
+	var a, p []float64
+	for i := idx1; i <= idx2; i++ {
+		if math.IsNaN(analog[i]) || math.IsNaN(photon[i]) || (method == GlueRatio && photon[i] == 0) {
+			continue
+		}
+		a = append(a, analog[i])
+		p = append(p, photon[i])
+	}
+
+	res := GlueResult{Method: method, Bins: len(a)}
+	dof := len(a) - 1
+	switch method {
+	case GlueRatio:
+		if len(a) == 0 {
+			return GlueResult{}, fmt.Errorf("all photon data values are zero, cannot compute coefficient")
+		}
+		var sumK float64
+		for i := range a {
+			sumK += a[i] / p[i]
+		}
+		res.K = sumK / float64(len(a))
+	case GlueRegression:
+		dof--
+		if dof < 1 {
+			return GlueResult{}, fmt.Errorf("%d bins are too few for regression", len(a))
+		}
+		if stdDev(p, mean(p)) == 0 {
+			return GlueResult{}, fmt.Errorf("photon signal is constant, cannot fit regression")
+		}
+		res.K, res.Offset = linearFit(p, a)
+	default:
+		return GlueResult{}, fmt.Errorf("unknown glue method %s", method)
+	}
+
+	am := mean(a)
+	var ssRes, ssTot float64
+	for i := range a {
+		d := a[i] - (res.K*p[i] + res.Offset)
+		ssRes += d * d
+		ssTot += (a[i] - am) * (a[i] - am)
+	}
+	switch {
+	case ssTot > 0:
+		res.R2 = 1 - ssRes/ssTot
+	case ssRes == 0:
+		res.R2 = 1
+	}
+	if dof > 0 {
+		res.Residual = math.Sqrt(ssRes / float64(dof))
+	}
+	return res, nil
+}
+
+// stdDev — выборочное СКО значений v относительно m; для менее чем двух значений — 0
+func stdDev(v []float64, m float64) float64 {
+	if len(v) < 2 {
+		return 0
+	}
+	var ss float64
+	for _, x := range v {
+		ss += (x - m) * (x - m)
+	}
+	return math.Sqrt(ss / float64(len(v)-1))
+}
@@ -0,0 +1,249 @@
+package licelformat
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// glueTestFile — фотонный канал 50·exp(−i/15) МГц (выше 10 МГц до бина 24 включительно)
+// и аналоговый 2·photon + 0.5 мВ с шумом амплитуды noise
+func glueTestFile(noise float64) LicelFile {
+	const n = 100
+	analog := LicelProfile{DeviceID: DeviceIDAnalog, Wavelength: 532, Polarization: "o", NShots: 20, BinWidth: 10, NDataPoints: n, Data: make([]float64, n)}
+	photon := LicelProfile{DeviceID: DeviceIDPhoton, Photon: true, Wavelength: 532, Polarization: "o", NShots: 20, BinWidth: 10, NDataPoints: n, Data: make([]float64, n)}
+	for i := range n {
+		photon.Data[i] = 50 * math.Exp(-float64(i)/15)
+		analog.Data[i] = 2*photon.Data[i] + 0.5 + noise*math.Sin(1.7*float64(i))
+	}
+	return LicelFile{MeasurementSite: "Site", NDatasets: 2, Profiles: LicelProfilesList{analog, photon}}
+}
+
+func TestGlueMethod_String(t *testing.T) {
+	assert.Equal(t, "ratio", GlueRatio.String())
+	assert.Equal(t, "regression", GlueRegression.String())
+	assert.Equal(t, "GlueMethod(7)", GlueMethod(7).String())
+
+	b, err := json.Marshal(GlueResult{Method: GlueRegression})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"method":"regression"`)
+}
+
+func TestGlueResult_JSON_RoundTrip(t *testing.T) {
+	lf := glueTestFile(0)
+	_, res, err := lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, Blend: BlendCosine, From: 300, To: 600})
+	require.NoError(t, err)
+
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	var back GlueResult
+	require.NoError(t, json.Unmarshal(b, &back))
+	assert.Equal(t, res, back)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"method":"spline"}`), &back))
+	assert.Error(t, json.Unmarshal([]byte(`{"blend":"GlueBlend(9)"}`), &back))
+}
+
+func TestLicelFile_GlueWith_Regression(t *testing.T) {
+	lf := glueTestFile(0)
+	glued, res, err := lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, From: 300, To: 600})
+	require.NoError(t, err)
+
+	assert.Equal(t, GlueRegression, res.Method)
+	assert.InDelta(t, 2, res.K, 1e-9)
+	assert.InDelta(t, 0.5, res.Offset, 1e-9)
+	assert.InDelta(t, 1, res.R2, 1e-12)
+	assert.InDelta(t, 0, res.Residual, 1e-9)
+	assert.Equal(t, 300.0, res.From)
+	assert.Equal(t, 600.0, res.To)
+
+	analog, photon := lf.Profiles[0].Data, lf.Profiles[1].Data
+	assert.Equal(t, analog[10], glued.Data[10])
+	assert.InDelta(t, 0.5*(analog[40]+2*photon[40]+0.5), glued.Data[40], 1e-9)
+	assert.InDelta(t, 2*photon[80]+0.5, glued.Data[80], 1e-9)
+	assert.True(t, glued.IsGlued())
+}
+
+func TestLicelFile_GlueWith_RatioMatchesGlue(t *testing.T) {
+	lf := glueTestFile(0)
+	legacy, _, err := lf.Glue(532, 300, 600, "o")
+	require.NoError(t, err)
+	glued, res, err := lf.GlueWith(532, "o", GlueOptions{From: 300, To: 600})
+	require.NoError(t, err)
+
+	assert.Equal(t, legacy, glued)
+	assert.Equal(t, GlueRatio, res.Method)
+	assert.Zero(t, res.Offset)
+	// ratio без смещения хуже описывает 2·photon + 0.5
+	assert.Less(t, res.R2, 1.0)
+	assert.Positive(t, res.Residual)
+}
+
+func TestLicelFile_GlueWith_AutoWindow(t *testing.T) {
+	lf := glueTestFile(0.01)
+	_, res, err := lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, AutoWindow: true})
+	require.NoError(t, err)
+
+	// первый бин с photon ≤ 10 МГц — 25-й; окно кончается раньше шумового хвоста
+	assert.Equal(t, 250.0, res.From)
+	assert.Greater(t, res.To, 600.0)
+	assert.Less(t, res.To, 900.0)
+	assert.InDelta(t, 2, res.K, 0.05)
+	assert.InDelta(t, 0.5, res.Offset, 0.05)
+	assert.Greater(t, res.R2, 0.999)
+
+	// границы поиска ограничивают окно
+	_, res, err = lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, AutoWindow: true, From: 400, To: 500})
+	require.NoError(t, err)
+	assert.Equal(t, 400.0, res.From)
+	assert.Equal(t, 500.0, res.To)
+}
+
+func TestLicelFile_GlueWith_Errors(t *testing.T) {
+	lf := glueTestFile(0.01)
+
+	_, _, err := lf.GlueWith(532, "o", GlueOptions{AutoWindow: true, MaxPhotonRate: 1e-6})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no window of 10 bins")
+
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{From: 500, To: 500})
+	assert.Error(t, err)
+
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, From: 500, To: 510})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "too few for regression")
+
+	for i := range lf.Profiles[1].Data {
+		lf.Profiles[1].Data[i] = 1
+	}
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, From: 300, To: 600})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "photon signal is constant")
+
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{Method: GlueMethod(7), From: 300, To: 600})
+	assert.Error(t, err)
+}
+
+func TestLicelPack_GlueWith(t *testing.T) {
+	pack := LicelPack{Data: map[string]LicelFile{"f1": glueTestFile(0), "f2": glueTestFile(0)}}
+
+	results, err := pack.GlueWith(532, "o", GlueOptions{Method: GlueRegression, From: 300, To: 600})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.InDelta(t, 2, results["f1"].K, 1e-9)
+
+	// повторная склейка заменяет профиль BG
+	_, err = pack.GlueWith(532, "o", GlueOptions{From: 300, To: 600})
+	require.NoError(t, err)
+	for _, lf := range pack.Data {
+		assert.Len(t, lf.Profiles, 3)
+		assert.Equal(t, 3, lf.NDatasets)
+	}
+
+	_, err = pack.GlueWith(355, "o", GlueOptions{From: 300, To: 600})
+	assert.Error(t, err)
+}
+
+func TestLicelPack_Glue_Atomic(t *testing.T) {
+	broken := glueTestFile(0)
+	broken.Profiles[1].Polarization = "p" // нет фотонного канала 532 o
+	pack := LicelPack{Data: map[string]LicelFile{"f1": glueTestFile(0), "f2": broken}}
+
+	results, err := pack.GlueWith(532, "o", GlueOptions{From: 300, To: 600})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "f2")
+	assert.Nil(t, results)
+	_, err = pack.Glue(532, 300, 600, "o")
+	require.Error(t, err)
+	for _, lf := range pack.Data {
+		assert.Len(t, lf.Profiles, 2)
+		assert.Equal(t, 2, lf.NDatasets)
+	}
+}
+
+func TestLicelFile_Glue_Result(t *testing.T) {
+	lf := glueTestFile(0)
+	lf.Profiles[1].Data[35] = 0 // бин без фотонов не участвует в расчёте k
+	glued, res, err := lf.Glue(532, 300, 400, "o")
+	require.NoError(t, err)
+
+	assert.Equal(t, GlueRatio, res.Method)
+	assert.Equal(t, 30, res.First)
+	assert.Equal(t, 40, res.Last)
+	assert.Equal(t, 10, res.Bins)
+	require.Len(t, res.Weights, len(glued.Data))
+	assert.Equal(t, 1.0, res.Weights[29])
+	assert.Equal(t, 0.5, res.Weights[30])
+	assert.Equal(t, 0.5, res.Weights[40])
+	assert.Equal(t, 0.0, res.Weights[41])
+
+	analog, photon := lf.Profiles[0].Data, lf.Profiles[1].Data
+	for i, w := range res.Weights {
+		assert.InDelta(t, w*analog[i]+(1-w)*res.K*photon[i], glued.Data[i], 1e-9, "bin %d", i)
+	}
+
+	b, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"first":30,"last":40`)
+	assert.Contains(t, string(b), `"bins":10`)
+}
+
+func TestGlueBlend_String(t *testing.T) {
+	assert.Equal(t, "mean", BlendMean.String())
+	assert.Equal(t, "linear", BlendLinear.String())
+	assert.Equal(t, "cosine", BlendCosine.String())
+	assert.Equal(t, "sigmoid", BlendSigmoid.String())
+	assert.Equal(t, "snr", BlendSNR.String())
+	assert.Equal(t, "GlueBlend(9)", GlueBlend(9).String())
+}
+
+func TestLicelFile_GlueWith_Blend(t *testing.T) {
+	lf := glueTestFile(0)
+	for _, blend := range []GlueBlend{BlendLinear, BlendCosine, BlendSigmoid} {
+		glued, res, err := lf.GlueWith(532, "o", GlueOptions{Blend: blend, From: 300, To: 400})
+		require.NoError(t, err, blend)
+		assert.Equal(t, blend, res.Blend)
+
+		// веса непрерывны на краях окна и монотонно убывают
+		assert.Equal(t, 1.0, res.Weights[30], blend)
+		assert.InDelta(t, 0.5, res.Weights[35], 1e-12, blend)
+		assert.InDelta(t, 0, res.Weights[40], 1e-12, blend)
+		for i := 31; i <= 40; i++ {
+			assert.Less(t, res.Weights[i], res.Weights[i-1], "%s bin %d", blend, i)
+		}
+
+		analog, photon := lf.Profiles[0].Data, lf.Profiles[1].Data
+		for i, w := range res.Weights {
+			assert.InDelta(t, w*analog[i]+(1-w)*res.K*photon[i], glued.Data[i], 1e-9, "%s bin %d", blend, i)
+		}
+	}
+
+	_, res, err := lf.GlueWith(532, "o", GlueOptions{Blend: BlendLinear, From: 300, To: 400})
+	require.NoError(t, err)
+	assert.InDelta(t, 0.8, res.Weights[32], 1e-12)
+
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{Blend: GlueBlend(9), From: 300, To: 400})
+	assert.Error(t, err)
+}
+
+func TestLicelFile_GlueWith_BlendSNR(t *testing.T) {
+	lf := glueTestFile(0.01)
+	_, res, err := lf.GlueWith(532, "o", GlueOptions{Method: GlueRegression, Blend: BlendSNR, From: 300, To: 600})
+	require.NoError(t, err)
+
+	// дисперсия фотонного канала падает с сигналом, поэтому вес аналогового канала убывает
+	for i := 31; i <= 60; i++ {
+		assert.Less(t, res.Weights[i], res.Weights[i-1], "bin %d", i)
+		assert.Greater(t, res.Weights[i], 0.0)
+		assert.Less(t, res.Weights[i], 1.0)
+	}
+	assert.Equal(t, 1.0, res.Weights[29])
+	assert.Equal(t, 0.0, res.Weights[61])
+
+	lf.Profiles[1].NShots = 0
+	_, _, err = lf.GlueWith(532, "o", GlueOptions{Blend: BlendSNR, From: 300, To: 600})
+	assert.Error(t, err)
+}
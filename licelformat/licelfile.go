@@ -2,10 +2,12 @@ package licelformat
 
 import (
 	"bufio"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -15,189 +17,564 @@ const (
 	LICEL_MAX_HEADER_LEN = 80
 )
 
+// maxHeaderLineLen — предельная длина строки заголовка вместе с окончанием строки.
+// С запасом покрывает расширенные варианты заголовка и не даёт файлу без переводов
+// строк заставить загрузчик читать его целиком в одну строку.
+const maxHeaderLineLen = 4096
+
+var errLineTooLong = fmt.Errorf("header line longer than %d bytes", maxHeaderLineLen)
+
+// LicelVariant — вариант формата заголовка LICEL-файла
+type LicelVariant int
+
+const (
+	VariantClassic LicelVariant = iota // три лазера: 7 полей в третьей строке
+	VariantLaser4                      // четыре лазера: 9 полей в третьей строке
+)
+
+// String — название варианта формата
+func (v LicelVariant) String() string {
+	switch v {
+	case VariantClassic:
+		return "classic"
+	case VariantLaser4:
+		return "laser4"
+	}
+	return fmt.Sprintf("LicelVariant(%d)", int(v))
+}
+
+// MarshalText — вариант в JSON выводится названием
+func (v LicelVariant) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText — вариант по названию из MarshalText
+func (v *LicelVariant) UnmarshalText(text []byte) error {
+	for x := VariantClassic; x <= VariantLaser4; x++ {
+		if x.String() == string(text) {
+			*v = x
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown licel variant %q", text)
+}
+
+// dateFieldRegex — поле даты dd/mm/yyyy во второй строке, отделяет название места от времени
+var dateFieldRegex = regexp.MustCompile(`^\d{2}/\d{2}/\d{4}$`)
+
 // LicelFile — структура, представляющая единичное измерение
 type LicelFile struct {
-	MeasurementSite       string            `json:"location"`       // Место измерения
-	MeasurementStartTime  time.Time         `json:"start_time"`     // Время начала измерения
-	MeasurementStopTime   time.Time         `json:"stop_time"`      // Время окончания измерения
-	AltitudeAboveSeaLevel float64           `json:"lidar_altitude"` // Высота над уровнем моря
-	Longitude             float64           `json:"longitude"`      // Долгота
-	Latitude              float64           `json:"latitude"`       // Широта
-	Zenith                float64           `json:"zenith"`         // Зенит
-	Laser1NShots          int               `json:"laser1_nshots"`  // Количество импульсов лазера 1
-	Laser1Freq            int               `json:"laser1_freq"`    // Частота лазера 1
-	Laser2NShots          int               `json:"laser2_nshots"`  // Количество импульсов лазера 2
-	Laser2Freq            int               `json:"laser2_freq"`    // Частота лазера 2
-	NDatasets             int               `json:"dataset_count"`  // Количество наборов данных
-	Laser3NShots          int               `json:"laser3_nshots"`  // Количество импульсов лазера 3
-	Laser3Freq            int               `json:"laser3_freq"`    // Частота лазера 3
-	FileLoaded            bool              `json:"-"`              // Файл загружен
-	Profiles              LicelProfilesList `json:"datasets"`       // Список профилей
-}
-
-// LoadLicelFile — загружает LICEL-файл по имени
-func LoadLicelFile(fname string) (LicelFile, error) {
+	FileName              string            `json:"file_name"`              // Имя файла из первой строки
+	MeasurementSite       string            `json:"location"`               // Место измерения
+	MeasurementStartTime  time.Time         `json:"start_time"`             // Время начала измерения
+	MeasurementStopTime   time.Time         `json:"stop_time"`              // Время окончания измерения
+	AltitudeAboveSeaLevel float64           `json:"lidar_altitude"`         // Высота над уровнем моря
+	Longitude             float64           `json:"longitude"`              // Долгота
+	Latitude              float64           `json:"latitude"`               // Широта
+	Zenith                float64           `json:"zenith"`                 // Зенит
+	Laser1NShots          int               `json:"laser1_nshots"`          // Количество импульсов лазера 1
+	Laser1Freq            int               `json:"laser1_freq"`            // Частота лазера 1
+	Laser2NShots          int               `json:"laser2_nshots"`          // Количество импульсов лазера 2
+	Laser2Freq            int               `json:"laser2_freq"`            // Частота лазера 2
+	NDatasets             int               `json:"dataset_count"`          // Количество наборов данных
+	Laser3NShots          int               `json:"laser3_nshots"`          // Количество импульсов лазера 3
+	Laser3Freq            int               `json:"laser3_freq"`            // Частота лазера 3
+	Laser4NShots          int               `json:"laser4_nshots"`          // Количество импульсов лазера 4 (VariantLaser4)
+	Laser4Freq            int               `json:"laser4_freq"`            // Частота лазера 4 (VariantLaser4)
+	Variant               LicelVariant      `json:"variant"`                // Вариант формата заголовка
+	HeaderExtra           []string          `json:"header_extra,omitempty"` // Дополнительные поля второй строки после зенита
+	LaserExtra            []string          `json:"laser_extra,omitempty"`  // Дополнительные поля третьей строки после параметров лазеров
+	Warnings              []ParseWarning    `json:"warnings,omitempty"`     // Проблемы, обойдённые нестрогим разбором (WithLenient)
+	FileLoaded            bool              `json:"-"`                      // Файл загружен
+	HeaderOnly            bool              `json:"-"`                      // Загружен только заголовок, данных профилей нет
+	Profiles              LicelProfilesList `json:"datasets"`               // Список профилей
+
+	rawLines [3]string // исходные строки 1–3 заголовка (с окончаниями строк)
+	rawKeys  [3]string // значения полей на момент загрузки, см. headerLine
+}
+
+// LoadLicelFile — загружает LICEL-файл по имени.
+// Времена заголовка считаются записанными в UTC, если не задано WithLocation.
+func LoadLicelFile(fname string, opts ...LoadOption) (LicelFile, error) {
 	f, err := os.Open(fname)
 	if err != nil {
 		return LicelFile{}, fmt.Errorf("opening file %q: %w", fname, err)
 	}
 	defer f.Close()
 
-	return loadFromReader(bufio.NewReader(f))
+	return loadFromReader(bufio.NewReader(f), newLoadOptions(opts))
 }
 
 // LoadLicelFileFromReader — загружает LICEL-файл из произвольного io.Reader
-func LoadLicelFileFromReader(r io.Reader) (LicelFile, error) {
-	return loadFromReader(bufio.NewReader(r))
+func LoadLicelFileFromReader(r io.Reader, opts ...LoadOption) (LicelFile, error) {
+	return loadFromReader(bufio.NewReader(r), newLoadOptions(opts))
+}
+
+// LoadLicelHeader — загружает только заголовок LICEL-файла (строки 1–3 и заголовки профилей).
+// Бинарные данные не читаются: Data профилей остаётся пустым, DataOffset указывает
+// смещение данных каждого профиля в файле. Результат помечен HeaderOnly и не может быть сохранён.
+// Равносильно LoadLicelFile с WithHeaderOnly.
+func LoadLicelHeader(fname string, opts ...LoadOption) (LicelFile, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return LicelFile{}, fmt.Errorf("opening file %q: %w", fname, err)
+	}
+	defer f.Close()
+
+	return LoadLicelHeaderFromReader(f, opts...)
+}
+
+// LoadLicelHeaderFromReader — загружает только заголовок LICEL-файла из io.Reader
+func LoadLicelHeaderFromReader(r io.Reader, opts ...LoadOption) (LicelFile, error) {
+	o := newLoadOptions(opts)
+	o.HeaderOnly = true
+	return loadFromReader(bufio.NewReader(r), o)
 }
 
 // loadFromReader — общая логика загрузки LICEL-файла
-func loadFromReader(r *bufio.Reader) (LicelFile, error) {
+func loadFromReader(r *bufio.Reader, o LoadOptions) (LicelFile, error) {
+	lr := newLicelReader(r)
 	var licf LicelFile
+	if err := parseHeader(lr, &licf, o); err != nil {
+		return licf, err
+	}
 
-	// Пропустить первую строку (обычно пустую или с именем файла)
-	if _, err := readAndTrimLine(r); err != nil {
-		return licf, fmt.Errorf("reading line 1: %w", err)
+	if o.HeaderOnly {
+		licf.filterProfiles(&o)
+		licf.HeaderOnly = true
+		return licf, nil
 	}
 
-	// Вторая строка: базовая информация
-	header, err := readAndTrimLine(r)
+	if o.Lenient {
+		readDataLenient(lr, &licf, &o)
+		licf.filterProfiles(&o)
+		licf.FileLoaded = true
+		return licf, nil
+	}
+
+	for i := 0; i < licf.NDatasets; i++ {
+		pr := &licf.Profiles[i]
+		if !o.keepProfile(pr) {
+			if err := lr.discard(pr.NDataPoints * 4); err != nil {
+				return licf, dataError(pr.DataOffset, i, err)
+			}
+		} else {
+			pr.Data = make([]float64, pr.NDataPoints)
+			if !o.DiscardRaw {
+				pr.Raw = make([]int32, pr.NDataPoints)
+			}
+			if _, err := lr.readSamples(pr.Data, pr.Raw, pr.scaleFactor()); err != nil {
+				return licf, dataError(pr.DataOffset, i, err)
+			}
+		}
+		if err := lr.skipCRLF(); err != nil {
+			return licf, dataError(lr.off, i, err)
+		}
+	}
+	licf.filterProfiles(&o)
+
+	licf.FileLoaded = true
+	return licf, nil
+}
+
+// readDataLenient — читает данные профилей, не прерываясь на повреждённых местах.
+// Профиль с неполными данными сохраняет прочитанные отсчёты и помечается Truncated;
+// профили, для которых данных нет совсем, отбрасываются. Все проблемы записываются в Warnings.
+func readDataLenient(lr *licelReader, licf *LicelFile, o *LoadOptions) {
+	n := 0 // число профилей, для которых есть данные
+	for i := range licf.Profiles {
+		pr := &licf.Profiles[i]
+		pr.DataOffset = lr.off
+		var points int
+		var err error
+		if o.keepProfile(pr) {
+			data := make([]float64, pr.NDataPoints)
+			var raw []int32
+			if !o.DiscardRaw {
+				raw = make([]int32, pr.NDataPoints)
+			}
+			points, err = lr.readSamples(data, raw, pr.scaleFactor())
+			if points > 0 {
+				pr.Data = data[:points]
+				if raw != nil {
+					pr.Raw = raw[:points]
+				}
+			}
+		} else {
+			var got int
+			got, err = lr.r.Discard(pr.NDataPoints * 4)
+			lr.off += int64(got)
+			points = got / 4
+		}
+		if err != nil {
+			licf.warn(WarnShortData, i, pr.DataOffset, "expected %d data points, got %d", pr.NDataPoints, points)
+			if points > 0 {
+				pr.NDataPoints = points
+				pr.Truncated = true
+				n++
+			}
+		} else {
+			n++
+		}
+		if err != nil {
+			break
+		}
+		if !lr.hasCRLF() {
+			licf.warn(WarnMissingCRLF, i, lr.off, "no CRLF after profile data")
+			continue
+		}
+		if err := lr.skipCRLF(); err != nil {
+			break
+		}
+	}
+	if n < len(licf.Profiles) {
+		licf.warn(WarnDatasetCount, -1, lr.off, "data found for %d of %d profiles", n, len(licf.Profiles))
+		licf.Profiles = licf.Profiles[:n]
+		licf.NDatasets = n
+	}
+}
+
+// filterProfiles — оставляет профили, прошедшие ProfileFilter, и обновляет NDatasets
+func (lf *LicelFile) filterProfiles(o *LoadOptions) {
+	if o.ProfileFilter == nil {
+		return
+	}
+	kept := make(LicelProfilesList, 0, len(lf.Profiles))
+	for i := range lf.Profiles {
+		if o.keepProfile(&lf.Profiles[i]) {
+			kept = append(kept, lf.Profiles[i])
+		}
+	}
+	lf.Profiles = kept
+	lf.NDatasets = len(kept)
+}
+
+// parseHeader — читает строки 1–3, заголовки профилей и разделитель перед бинарными данными.
+// Заполняет DataOffset каждого профиля. Ошибки возвращаются как *ParseError.
+func parseHeader(lr *licelReader, licf *LicelFile, o LoadOptions) error {
+	// Первая строка: имя файла (может быть пустой)
+	raw, err := lr.readRawLine()
 	if err != nil {
-		return licf, fmt.Errorf("reading line 2: %w", err)
+		return lr.lineError(err)
 	}
-	tmp := strings.Fields(header)
-	if len(tmp) < 9 {
-		return licf, fmt.Errorf("line 2: expected at least 9 fields, got %d", len(tmp))
+	licf.FileName = strings.TrimSpace(raw)
+	licf.rawLines[0], licf.rawKeys[0] = raw, licf.FileName
+
+	// Вторая строка: базовая информация
+	raw, err = lr.readRawLine()
+	if err != nil {
+		return lr.lineError(err)
 	}
+	tmp := strings.Fields(raw)
 
-	licf.MeasurementSite = tmp[0]
+	// Название места может содержать пробелы: оно занимает все поля до даты начала
+	siteLen := 0
+	for siteLen < len(tmp) && !dateFieldRegex.MatchString(tmp[siteLen]) {
+		siteLen++
+	}
+	if siteLen == len(tmp) {
+		return lr.locate(headerError("start date not found"))
+	}
+	licf.MeasurementSite = strings.Join(tmp[:siteLen], " ")
+	tmp = tmp[siteLen:]
+	if len(tmp) < 8 {
+		return lr.locate(headerError("expected at least 8 fields after site, got %d", len(tmp)))
+	}
 
-	licf.MeasurementStartTime, err = parseTime(tmp[1] + " " + tmp[2])
+	licf.MeasurementStartTime, err = parseTime(tmp[0]+" "+tmp[1], o.Location)
 	if err != nil {
-		return licf, fmt.Errorf("parsing start time: %w", err)
+		return lr.locate(fieldError("start time", tmp[0]+" "+tmp[1], err))
 	}
-	licf.MeasurementStopTime, err = parseTime(tmp[3] + " " + tmp[4])
+	licf.MeasurementStopTime, err = parseTime(tmp[2]+" "+tmp[3], o.Location)
 	if err != nil {
-		return licf, fmt.Errorf("parsing stop time: %w", err)
+		return lr.locate(fieldError("stop time", tmp[2]+" "+tmp[3], err))
 	}
 
 	var fErr error
-	licf.AltitudeAboveSeaLevel, fErr = str2Float(tmp[5])
+	licf.AltitudeAboveSeaLevel, fErr = str2Float(tmp[4])
 	if fErr != nil {
-		return licf, fmt.Errorf("parsing altitude: %w", fErr)
+		return lr.locate(fieldError("altitude", tmp[4], fErr))
 	}
-	licf.Longitude, fErr = str2Float(tmp[6])
+	licf.Longitude, fErr = str2Float(tmp[5])
 	if fErr != nil {
-		return licf, fmt.Errorf("parsing longitude: %w", fErr)
+		return lr.locate(fieldError("longitude", tmp[5], fErr))
 	}
-	licf.Latitude, fErr = str2Float(tmp[7])
+	licf.Latitude, fErr = str2Float(tmp[6])
 	if fErr != nil {
-		return licf, fmt.Errorf("parsing latitude: %w", fErr)
+		return lr.locate(fieldError("latitude", tmp[6], fErr))
 	}
-	licf.Zenith, fErr = str2Float(tmp[8])
+	licf.Zenith, fErr = str2Float(tmp[7])
 	if fErr != nil {
-		return licf, fmt.Errorf("parsing zenith: %w", fErr)
+		return lr.locate(fieldError("zenith", tmp[7], fErr))
 	}
+	if len(tmp) > 8 {
+		licf.HeaderExtra = tmp[8:]
+	}
+	licf.rawLines[1], licf.rawKeys[1] = raw, licf.secondLineKey(o.Location)
 
 	// Третья строка: параметры лазеров
-	header, err = readAndTrimLine(r)
+	raw, err = lr.readRawLine()
 	if err != nil {
-		return licf, fmt.Errorf("reading line 3: %w", err)
+		return lr.lineError(err)
 	}
-	tmp = strings.Fields(header)
+	tmp = strings.Fields(raw)
 	if len(tmp) < 7 {
-		return licf, fmt.Errorf("line 3: expected at least 7 fields, got %d", len(tmp))
+		return lr.locate(headerError("expected at least 7 fields, got %d", len(tmp)))
 	}
 
 	var iErr error
 	licf.Laser1NShots, iErr = str2Int(tmp[0])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser1 nshots: %w", iErr)
+		return lr.locate(fieldError("laser1 nshots", tmp[0], iErr))
 	}
 	licf.Laser1Freq, iErr = str2Int(tmp[1])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser1 freq: %w", iErr)
+		return lr.locate(fieldError("laser1 freq", tmp[1], iErr))
 	}
 	licf.Laser2NShots, iErr = str2Int(tmp[2])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser2 nshots: %w", iErr)
+		return lr.locate(fieldError("laser2 nshots", tmp[2], iErr))
 	}
 	licf.Laser2Freq, iErr = str2Int(tmp[3])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser2 freq: %w", iErr)
+		return lr.locate(fieldError("laser2 freq", tmp[3], iErr))
 	}
 	licf.NDatasets, iErr = str2Int(tmp[4])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing dataset count: %w", iErr)
+		return lr.locate(fieldError("dataset count", tmp[4], iErr))
+	}
+	if licf.NDatasets < 0 {
+		pe := lr.locate(fieldError("dataset count", tmp[4], errors.New("must not be negative")))
+		pe.Kind = ErrDatasetCount
+		return pe
+	}
+	if exceeds(int64(licf.NDatasets), int64(o.Limits.MaxDatasets)) {
+		return lr.locate(limitError("dataset count", int64(licf.NDatasets), int64(o.Limits.MaxDatasets)))
 	}
 	licf.Laser3NShots, iErr = str2Int(tmp[5])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser3 nshots: %w", iErr)
+		return lr.locate(fieldError("laser3 nshots", tmp[5], iErr))
 	}
 	licf.Laser3Freq, iErr = str2Int(tmp[6])
 	if iErr != nil {
-		return licf, fmt.Errorf("parsing laser3 freq: %w", iErr)
+		return lr.locate(fieldError("laser3 freq", tmp[6], iErr))
+	}
+
+	// Новые версии Licel Acquis добавляют четвёртый лазер
+	extra := tmp[7:]
+	if len(tmp) >= 9 {
+		licf.Variant = VariantLaser4
+		licf.Laser4NShots, iErr = str2Int(tmp[7])
+		if iErr != nil {
+			return lr.locate(fieldError("laser4 nshots", tmp[7], iErr))
+		}
+		licf.Laser4Freq, iErr = str2Int(tmp[8])
+		if iErr != nil {
+			return lr.locate(fieldError("laser4 freq", tmp[8], iErr))
+		}
+		extra = tmp[9:]
+	}
+	if len(extra) > 0 {
+		licf.LaserExtra = extra
 	}
+	licf.rawLines[2], licf.rawKeys[2] = raw, licf.thirdLineKey()
 
 	// Профили
-	licf.Profiles = make(LicelProfilesList, licf.NDatasets)
+	licf.Profiles = make(LicelProfilesList, 0, licf.NDatasets)
+	separatorRead := false
 	for i := 0; i < licf.NDatasets; i++ {
-		header, err = readAndTrimLine(r)
+		raw, err = lr.readRawLine()
 		if err != nil {
-			return licf, fmt.Errorf("reading profile header %d: %w", i, err)
+			return atPosition(lr.lineError(err), lr.line, lr.lineOff, i)
+		}
+		// Разделитель раньше, чем заявлено в NDatasets
+		if strings.TrimSpace(raw) == "" {
+			if !o.Lenient {
+				return lr.countError("header declares %d profiles, found %d", licf.NDatasets, i)
+			}
+			licf.warn(WarnDatasetCount, -1, lr.lineOff, "header declares %d profiles, found %d", licf.NDatasets, i)
+			separatorRead = true
+			break
 		}
-		licf.Profiles[i], err = newLicelProfile(header)
+		pr, err := newLicelProfile(raw)
 		if err != nil {
-			return licf, fmt.Errorf("parsing profile %d: %w", i, err)
+			return atPosition(err, lr.line, lr.lineOff, i)
+		}
+		if exceeds(int64(pr.NDataPoints), int64(o.Limits.MaxDataPoints)) {
+			return atPosition(limitError("data points", int64(pr.NDataPoints), int64(o.Limits.MaxDataPoints)), lr.line, lr.lineOff, i)
 		}
+		pr.rawLine, pr.rawKey = raw, pr.headerKey()
+		licf.Profiles = append(licf.Profiles, pr)
 	}
 
-	// После заголовков — бинарные данные
-	if err := skipCRLF(r); err != nil {
-		return licf, fmt.Errorf("skipping header/body separator: %w", err)
+	// Заголовки профилей сверх NDatasets перед разделителем
+	if !separatorRead && !lr.hasCRLF() {
+		if !o.Lenient {
+			if _, err := lr.r.Peek(1); err != nil {
+				return readError(lr.line+1, lr.off, -1, err)
+			}
+			return lr.countError("expected empty line after %d profile headers", licf.NDatasets)
+		}
+		lineOff := lr.off
+		for !lr.hasCRLF() {
+			raw, err = lr.readRawLine()
+			if err != nil {
+				return atPosition(lr.lineError(err), lr.line, lr.lineOff, len(licf.Profiles))
+			}
+			if strings.TrimSpace(raw) == "" {
+				separatorRead = true
+				break
+			}
+			if exceeds(int64(len(licf.Profiles)+1), int64(o.Limits.MaxDatasets)) {
+				return atPosition(limitError("dataset count", int64(len(licf.Profiles)+1), int64(o.Limits.MaxDatasets)), lr.line, lr.lineOff, len(licf.Profiles))
+			}
+			pr, err := newLicelProfile(raw)
+			if err != nil {
+				return atPosition(err, lr.line, lr.lineOff, len(licf.Profiles))
+			}
+			if exceeds(int64(pr.NDataPoints), int64(o.Limits.MaxDataPoints)) {
+				return atPosition(limitError("data points", int64(pr.NDataPoints), int64(o.Limits.MaxDataPoints)), lr.line, lr.lineOff, len(licf.Profiles))
+			}
+			pr.rawLine, pr.rawKey = raw, pr.headerKey()
+			licf.Profiles = append(licf.Profiles, pr)
+		}
+		licf.warn(WarnDatasetCount, -1, lineOff, "header declares %d profiles, found %d", licf.NDatasets, len(licf.Profiles))
 	}
+	licf.NDatasets = len(licf.Profiles)
 
-	for i := 0; i < licf.NDatasets; i++ {
-		prTmp := make([]byte, licf.Profiles[i].NDataPoints*4)
-		if _, err := io.ReadFull(r, prTmp); err != nil {
-			return licf, fmt.Errorf("reading binary data for profile %d: %w", i, err)
+	// После заголовков — бинарные данные
+	if !separatorRead {
+		if err := lr.skipCRLF(); err != nil {
+			return readError(lr.line+1, lr.off, -1, err)
 		}
-		licf.Profiles[i].Data = bytesToFloat64Array(prTmp)
+		lr.line++
+	}
 
-		scale := licf.Profiles[i].scaleFactor()
-		for j := range licf.Profiles[i].Data {
-			licf.Profiles[i].Data[j] *= scale
+	// Каждый профиль: NDataPoints*4 байт данных и CRLF
+	offset := lr.off
+	for i := range licf.Profiles {
+		licf.Profiles[i].DataOffset = offset
+		offset += int64(licf.Profiles[i].NDataPoints)*4 + 2
+	}
+	if exceeds(offset, o.Limits.MaxFileSize) {
+		return &ParseError{Kind: ErrLimitExceeded, Line: lr.line, Offset: lr.off, Field: "file size", Profile: -1,
+			Err: fmt.Errorf("header describes %d bytes, limit is %d", offset, o.Limits.MaxFileSize)}
+	}
+
+	return nil
+}
+
+// licelReader — bufio.Reader с подсчётом смещения от начала файла и строк заголовка
+type licelReader struct {
+	r       *bufio.Reader
+	off     int64 // число прочитанных байт
+	line    int   // число прочитанных строк
+	lineOff int64 // смещение начала последней прочитанной строки
+}
+
+func newLicelReader(r *bufio.Reader) *licelReader {
+	return &licelReader{r: r}
+}
+
+// readRawLine — читает строку вместе с окончанием строки.
+// Строки длиннее maxHeaderLineLen не накапливаются: возвращается errLineTooLong.
+func (lr *licelReader) readRawLine() (string, error) {
+	lr.line++
+	lr.lineOff = lr.off
+	var line []byte
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		lr.off += int64(len(chunk))
+		if len(line)+len(chunk) > maxHeaderLineLen {
+			return "", errLineTooLong
 		}
-		if err := skipCRLF(r); err != nil {
-			return licf, fmt.Errorf("skipping post-profile %d CRLF: %w", i, err)
+		line = append(line, chunk...)
+		switch err {
+		case nil:
+			return string(line), nil
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return "", err
 		}
 	}
+}
 
-	licf.FileLoaded = true
-	return licf, nil
+// lineError — ошибка чтения текущей строки заголовка
+func (lr *licelReader) lineError(err error) *ParseError {
+	return readError(lr.line, lr.lineOff, -1, err)
 }
 
-// readAndTrimLine — reads a line from reader and trims whitespace from right
-func readAndTrimLine(r *bufio.Reader) (string, error) {
-	line, err := r.ReadString('\n')
-	if err != nil {
-		return "", err
-	}
-	return strings.TrimRight(line, "\t\r "), nil
+// locate — привязывает ошибку разбора к последней прочитанной строке
+func (lr *licelReader) locate(pe *ParseError) *ParseError {
+	pe.Line, pe.Offset = lr.line, lr.lineOff
+	return pe
 }
 
-// skipCRLF — skips \r\n
-func skipCRLF(r *bufio.Reader) error {
-	crlf := make([]byte, 2)
-	if _, err := io.ReadFull(r, crlf); err != nil {
-		return err
+// countError — несовпадение числа заголовков профилей с NDatasets
+func (lr *licelReader) countError(format string, args ...any) *ParseError {
+	return &ParseError{Kind: ErrDatasetCount, Line: lr.line, Offset: lr.lineOff, Profile: -1, Err: fmt.Errorf(format, args...)}
+}
+
+// readFull — читает ровно len(buf) байт
+func (lr *licelReader) readFull(buf []byte) error {
+	n, err := io.ReadFull(lr.r, buf)
+	lr.off += int64(n)
+	return err
+}
+
+// discard — пропускает n байт
+func (lr *licelReader) discard(n int) error {
+	m, err := lr.r.Discard(n)
+	lr.off += int64(m)
+	return err
+}
+
+// readSamples — декодирует len(data) отсчётов прямо из буфера bufio.Reader, без промежуточного
+// []byte и отдельного прохода масштабирования (см. decodeSamples); raw может быть nil.
+// Возвращает число декодированных отсчётов; при нехватке данных — io.ErrUnexpectedEOF
+// (или io.EOF, если не прочитано ни байта), неполный последний отсчёт пропускается.
+func (lr *licelReader) readSamples(data []float64, raw []int32, scale float64) (int, error) {
+	chunk := lr.r.Size() &^ 3
+	n := 0
+	for n < len(data) {
+		want := min(4*(len(data)-n), chunk)
+		b, err := lr.r.Peek(want)
+		m := len(b) / 4
+		var rawPart []int32
+		if raw != nil {
+			rawPart = raw[n : n+m]
+		}
+		decodeSamples(data[n:n+m], rawPart, b, scale)
+		n += m
+		if dErr := lr.discard(len(b)); dErr != nil && err == nil {
+			err = dErr
+		}
+		if err != nil {
+			if err == io.EOF && (n > 0 || len(b) > 0) {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
 	}
-	return nil
+	return n, nil
+}
+
+// hasCRLF — следует ли за текущей позицией \r\n (без чтения)
+func (lr *licelReader) hasCRLF() bool {
+	b, err := lr.r.Peek(2)
+	return err == nil && b[0] == '\r' && b[1] == '\n'
 }
 
-// parseTime — parse datetime string "dd/mm/yyyy hh:mm:ss"
-func parseTime(s string) (time.Time, error) {
-	return time.ParseInLocation("02/01/2006 15:04:05", s, time.Local)
+// skipCRLF — пропускает \r\n
+func (lr *licelReader) skipCRLF() error {
+	var crlf [2]byte
+	return lr.readFull(crlf[:])
+}
+
+// parseTime — parse datetime string "dd/mm/yyyy hh:mm:ss" in location loc
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("02/01/2006 15:04:05", s, loc)
 }
 
 // str2Bool — converts string to bool
@@ -218,11 +595,8 @@ func str2Float(str string) (float64, error) {
 
 // bytesToFloat64Array — converts []byte to []float64 (little-endian int32 → float64)
 func bytesToFloat64Array(b []byte) []float64 {
-	n := len(b) / 4
-	arr := make([]float64, n)
-	for i := 0; i < n; i++ {
-		arr[i] = float64(int32(binary.LittleEndian.Uint32(b[i*4 : (i+1)*4])))
-	}
+	arr := make([]float64, len(b)/4)
+	decodeSamples(arr, nil, b, 1)
 	return arr
 }
 
@@ -231,7 +605,7 @@ func bytesToFloat64Array(b []byte) []float64 {
 // Returns (LicelProfile{}, false) if no match found.
 func (lf *LicelFile) SelectProfile(isPhoton bool, wavelength float64, polarization string) (LicelProfile, bool) {
 	for _, v := range lf.Profiles {
-		if v.IsGlued() {
+		if v.IsGlued() || v.IsRangeCorrected() {
 			continue
 		}
 		if v.IsPhoton() == isPhoton && v.Wavelength == wavelength {
@@ -247,7 +621,8 @@ func (lf *LicelFile) SelectProfile(isPhoton bool, wavelength float64, polarizati
 //
 // Параметры:
 //   - wvl — длина волны
-//   - h1, h2 — диапазон высот в метрах для вычисления коэффициента склейки
+//   - h1, h2 — диапазон дальностей в метрах для вычисления коэффициента склейки;
+//     бины определяются RangeIndex аналогового канала (с учётом BinShift/DecBinShift)
 //
 // Алгоритм:
 //  1. Находит аналоговый (Photon=false) и цифровой (Photon=true) профили.
@@ -256,90 +631,15 @@ func (lf *LicelFile) SelectProfile(isPhoton bool, wavelength float64, polarizati
 //     - h < h1: данные аналогового канала
 //     - h1 ≤ h ≤ h2: 0.5*(analog + k*photon)
 //     - h > h2: k*photon
-func (lf *LicelFile) Glue(wvl float64, h1, h2 float64, polarization string) (LicelProfile, error) {
-	analog, ok := lf.SelectProfile(false, wvl, polarization)
-	if !ok {
-		return LicelProfile{}, fmt.Errorf("glue: analog channel not found for wavelength %.0f", wvl)
-	}
-	photon, ok := lf.SelectProfile(true, wvl, polarization)
-	if !ok {
-		return LicelProfile{}, fmt.Errorf("glue: photon channel not found for wavelength %.0f", wvl)
-	}
-
-	if analog.BinWidth <= 0 {
-		return LicelProfile{}, fmt.Errorf("glue: analog channel has invalid bin width %.2f", analog.BinWidth)
-	}
-	if photon.BinWidth <= 0 {
-		return LicelProfile{}, fmt.Errorf("glue: photon channel has invalid bin width %.2f", photon.BinWidth)
-	}
-
+//  4. Возвращает профиль вместе с GlueResult: k, индексы и дальности окна, число
+//     использованных бинов и веса аналогового канала по бинам.
+//
+// Другие способы расчёта и автоматический выбор окна — в GlueWith.
+func (lf *LicelFile) Glue(wvl float64, h1, h2 float64, polarization string) (LicelProfile, GlueResult, error) {
 	if h1 >= h2 {
-		return LicelProfile{}, fmt.Errorf("glue: h1 (%.2f) must be less than h2 (%.2f)", h1, h2)
+		return LicelProfile{}, GlueResult{}, fmt.Errorf("glue: h1 (%.2f) must be less than h2 (%.2f)", h1, h2)
 	}
-
-	idx1 := int(h1 / analog.BinWidth)
-	idx2 := int(h2 / analog.BinWidth)
-
-	dataLen := len(analog.Data)
-	if len(photon.Data) < dataLen {
-		dataLen = len(photon.Data)
-	}
-
-	if idx1 < 0 || idx1 >= dataLen {
-		return LicelProfile{}, fmt.Errorf("glue: h1 (%.2f m) maps to index %d, out of range [0, %d)", h1, idx1, dataLen)
-	}
-	if idx2 >= dataLen {
-		return LicelProfile{}, fmt.Errorf("glue: h2 (%.2f m) maps to index %d, exceeds data length %d", h2, idx2, dataLen)
-	}
-
-	// Вычисляем k как среднее отношение analog/photon на [idx1:idx2]
-	n := 0
-	var sumK float64
-	for i := idx1; i <= idx2; i++ {
-		if photon.Data[i] == 0 {
-			continue
-		}
-		sumK += analog.Data[i] / photon.Data[i]
-		n++
-	}
-	if n == 0 {
-		return LicelProfile{}, fmt.Errorf("glue: all photon data values are zero in range [%.2f, %.2f], cannot compute coefficient", h1, h2)
-	}
-	k := sumK / float64(n)
-
-	// Создаём результирующий профиль
-	result := LicelProfile{
-		Active:       true,
-		Photon:       false,
-		LaserType:    analog.LaserType,
-		NDataPoints:  dataLen,
-		Reserved:     analog.Reserved,
-		HighVoltage:  analog.HighVoltage,
-		BinWidth:     analog.BinWidth,
-		Wavelength:   analog.Wavelength,
-		Polarization: analog.Polarization,
-		BinShift:     analog.BinShift,
-		DecBinShift:  analog.DecBinShift,
-		AdcBits:      analog.AdcBits,
-		NShots:       analog.NShots,
-		DiscrLevel:   analog.DiscrLevel,
-		DeviceID:     "BG",
-		NCrate:       analog.NCrate,
-		Data:         make([]float64, dataLen),
-	}
-
-	for i := 0; i < dataLen; i++ {
-		switch {
-		case i < idx1:
-			result.Data[i] = analog.Data[i]
-		case i <= idx2:
-			result.Data[i] = 0.5 * (analog.Data[i] + k*photon.Data[i])
-		default:
-			result.Data[i] = k * photon.Data[i]
-		}
-	}
-
-	return result, nil
+	return lf.GlueWith(wvl, polarization, GlueOptions{From: h1, To: h2})
 }
 
 // SetMaxDist обрезает все профили до дальности alt (метры).
@@ -352,52 +652,98 @@ func (lf *LicelFile) SetMaxDist(alt float64) error {
 	return nil
 }
 
-// WriteTo — сериализует LICEL-файл в io.Writer
-func (lf *LicelFile) WriteTo(w io.Writer, fname string) error {
-	bw := bufio.NewWriter(w)
+// WriteTo — сериализует LICEL-файл в io.Writer.
+// fname записывается в первую строку; пустая строка означает FileName.
+// Строки заголовка, поля которых не менялись после загрузки, выводятся в исходном виде.
+// Длина Data каждого профиля должна совпадать с NDataPoints. Запись идёт через LicelWriter.
+// Времена записываются в поясе, в котором файл был загружен (WithLocation, по умолчанию UTC),
+// если не задано WithWriteLocation, поэтому загруженный файл сохраняется без изменений.
+func (lf *LicelFile) WriteTo(w io.Writer, fname string, opts ...WriteOption) error {
+	if lf.HeaderOnly {
+		return errHeaderOnly
+	}
+	lw, err := NewLicelWriter(w, lf, fname, opts...)
+	if err != nil {
+		return err
+	}
+	for i := range lf.Profiles {
+		if _, err := lw.nextProfile(len(lf.Profiles[i].Data)); err != nil {
+			return err
+		}
+		if err := lw.writeProfile(&lf.Profiles[i]); err != nil {
+			return err
+		}
+	}
+	return lw.Close()
+}
 
-	if _, err := bw.WriteString(lf.formatFirstLine(fname)); err != nil {
-		return fmt.Errorf("writing line 1: %w", err)
+// errHeaderOnly — запись файла, загруженного без данных профилей
+var errHeaderOnly = errors.New("file was loaded header-only, profile data is not available")
+
+// Save — сохраняет LICEL-файл на диск. Файл пишется во временный файл рядом с fname
+// и переименовывается только после успешной записи, поэтому при ошибке (в том числе
+// для файла, загруженного WithHeaderOnly) существующий fname не изменяется.
+func (lf *LicelFile) Save(fname string, opts ...WriteOption) error {
+	if lf.HeaderOnly {
+		return errHeaderOnly
 	}
-	if _, err := bw.WriteString(lf.formatSecondLine()); err != nil {
-		return fmt.Errorf("writing line 2: %w", err)
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(fname); err == nil {
+		mode = fi.Mode().Perm()
 	}
-	if _, err := bw.WriteString(lf.formatThirdLine()); err != nil {
-		return fmt.Errorf("writing line 3: %w", err)
+	file, err := os.CreateTemp(filepath.Dir(fname), "."+filepath.Base(fname)+".*")
+	if err != nil {
+		return fmt.Errorf("creating file %q: %w", fname, err)
 	}
-	for i, p := range lf.Profiles {
-		if _, err := bw.WriteString(p.metadata()); err != nil {
-			return fmt.Errorf("writing metadata for profile %d: %w", i, err)
-		}
+	tmp := file.Name()
+	defer os.Remove(tmp)
+
+	if err := lf.WriteTo(file, filepath.Base(fname), opts...); err != nil {
+		file.Close()
+		return err
 	}
-	if _, err := bw.WriteString("\r\n"); err != nil {
-		return fmt.Errorf("writing header/body separator: %w", err)
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return fmt.Errorf("writing file %q: %w", fname, err)
 	}
-	for i, p := range lf.Profiles {
-		data, err := p.profileRaw()
-		if err != nil {
-			return fmt.Errorf("serializing profile %d: %w", i, err)
-		}
-		if _, err := bw.Write(data); err != nil {
-			return fmt.Errorf("writing binary data for profile %d: %w", i, err)
-		}
-		if _, err := bw.WriteString("\r\n"); err != nil {
-			return fmt.Errorf("writing post-profile %d CRLF: %w", i, err)
-		}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("writing file %q: %w", fname, err)
 	}
-
-	return bw.Flush()
+	if err := os.Rename(tmp, fname); err != nil {
+		return fmt.Errorf("writing file %q: %w", fname, err)
+	}
+	return nil
 }
 
-// Save — сохраняет LICEL-файл на диск
-func (lf *LicelFile) Save(fname string) error {
-	file, err := os.Create(fname)
-	if err != nil {
-		return fmt.Errorf("creating file %q: %w", fname, err)
+// headerLine — возвращает исходную строку заголовка idx, если key совпадает со значением
+// на момент загрузки, иначе — заново сформированную строку line
+func (lf *LicelFile) headerLine(idx int, key, line string) string {
+	if lf.rawLines[idx] != "" && lf.rawKeys[idx] == key {
+		return lf.rawLines[idx]
 	}
-	defer file.Close()
+	return line
+}
 
-	return lf.WriteTo(file, fname)
+// secondLineKey — значения полей второй строки для обнаружения изменений.
+// Времена берутся в часовом поясе loc, поэтому смена пояса при записи меняет ключ.
+func (lf *LicelFile) secondLineKey(loc *time.Location) string {
+	return fmt.Sprintf("%s|%s|%s|%v|%v|%v|%v|%q",
+		lf.MeasurementSite,
+		lf.MeasurementStartTime.In(loc).Format(time.RFC3339Nano),
+		lf.MeasurementStopTime.In(loc).Format(time.RFC3339Nano),
+		lf.AltitudeAboveSeaLevel, lf.Longitude, lf.Latitude, lf.Zenith,
+		lf.HeaderExtra)
+}
+
+// thirdLineKey — значения полей третьей строки для обнаружения изменений
+func (lf *LicelFile) thirdLineKey() string {
+	return fmt.Sprintf("%d|%d|%d|%d|%d|%d|%d|%t|%d|%d|%q",
+		lf.Laser1NShots, lf.Laser1Freq,
+		lf.Laser2NShots, lf.Laser2Freq,
+		lf.NDatasets,
+		lf.Laser3NShots, lf.Laser3Freq,
+		lf.hasLaser4(), lf.Laser4NShots, lf.Laser4Freq,
+		lf.LaserExtra)
 }
 
 // FormatFirstLine — форматирует первую строку LICEL-файла
@@ -405,19 +751,23 @@ func (lf *LicelFile) formatFirstLine(fname string) string {
 	return fmt.Sprintf(" %-77s\r\n", fname)
 }
 
-// FormatSecondLine — форматирует вторую строку LICEL-файла (метаданные измерения)
-func (lf *LicelFile) formatSecondLine() string {
+// FormatSecondLine — форматирует вторую строку LICEL-файла (метаданные измерения),
+// времена записываются в часовом поясе loc
+func (lf *LicelFile) formatSecondLine(loc *time.Location) string {
 	s := fmt.Sprintf(" %s %s %s %s %s %04.0f %06.1f %06.1f %02.0f",
 		lf.MeasurementSite,
-		lf.MeasurementStartTime.Local().Format("02/01/2006"),
-		lf.MeasurementStartTime.Local().Format("15:04:05"),
-		lf.MeasurementStopTime.Local().Format("02/01/2006"),
-		lf.MeasurementStopTime.Local().Format("15:04:05"),
+		lf.MeasurementStartTime.In(loc).Format("02/01/2006"),
+		lf.MeasurementStartTime.In(loc).Format("15:04:05"),
+		lf.MeasurementStopTime.In(loc).Format("02/01/2006"),
+		lf.MeasurementStopTime.In(loc).Format("15:04:05"),
 		lf.AltitudeAboveSeaLevel,
 		lf.Longitude,
 		lf.Latitude,
 		lf.Zenith,
 	)
+	if len(lf.HeaderExtra) > 0 {
+		s += " " + strings.Join(lf.HeaderExtra, " ")
+	}
 	return fmt.Sprintf("%-78s\r\n", s)
 }
 
@@ -429,5 +779,17 @@ func (lf *LicelFile) formatThirdLine() string {
 		lf.NDatasets,
 		lf.Laser3NShots, lf.Laser3Freq,
 	)
+	if lf.hasLaser4() {
+		s += fmt.Sprintf(" %07d %04d", lf.Laser4NShots, lf.Laser4Freq)
+	}
+	if len(lf.LaserExtra) > 0 {
+		s += " " + strings.Join(lf.LaserExtra, " ")
+	}
 	return fmt.Sprintf("%-78s\r\n", s)
 }
+
+// hasLaser4 — нужно ли записывать параметры четвёртого лазера.
+// Ненулевые значения записываются и для VariantClassic, чтобы не потерять их.
+func (lf *LicelFile) hasLaser4() bool {
+	return lf.Variant == VariantLaser4 || lf.Laser4NShots != 0 || lf.Laser4Freq != 0
+}
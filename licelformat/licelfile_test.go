@@ -1,8 +1,13 @@
 package licelformat
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -83,7 +88,7 @@ func TestScaleFactor_Analog(t *testing.T) {
 // --- parseTime ---
 
 func TestParseTime(t *testing.T) {
-	ts, err := parseTime("10/02/2020 19:22:35")
+	ts, err := parseTime("10/02/2020 19:22:35", time.UTC)
 	require.NoError(t, err)
 	assert.Equal(t, 2020, ts.Year())
 	assert.Equal(t, time.February, ts.Month())
@@ -91,17 +96,17 @@ func TestParseTime(t *testing.T) {
 	assert.Equal(t, 19, ts.Hour())
 	assert.Equal(t, 22, ts.Minute())
 	assert.Equal(t, 35, ts.Second())
-	assert.Equal(t, time.Local, ts.Location())
+	assert.Equal(t, time.UTC, ts.Location())
 }
 
 func TestParseTime_Invalid(t *testing.T) {
-	_, err := parseTime("not a date")
+	_, err := parseTime("not a date", time.UTC)
 	assert.Error(t, err)
 }
 
 func TestParseTime_WrongOrder(t *testing.T) {
 	// MM/DD/YYYY instead of DD/MM/YYYY
-	_, err := parseTime("30/02/2020 19:22:35")
+	_, err := parseTime("30/02/2020 19:22:35", time.UTC)
 	assert.Error(t, err)
 }
 
@@ -275,7 +280,7 @@ func TestFormatSecondLine(t *testing.T) {
 		Latitude:              43.1,
 		Zenith:                50,
 	}
-	s := lf.formatSecondLine()
+	s := lf.formatSecondLine(time.UTC)
 	assert.Contains(t, s, "Test")
 	assert.Contains(t, s, "0131.9")
 	assert.Contains(t, s, "0043.1")
@@ -316,7 +321,7 @@ func TestLicelFile_Glue_Success(t *testing.T) {
 		},
 	}
 
-	got, err := lf.Glue(532, 150, 300, "p")
+	got, _, err := lf.Glue(532, 150, 300, "p")
 	require.NoError(t, err)
 
 	assert.Equal(t, "BG", got.DeviceID)
@@ -352,13 +357,37 @@ func TestLicelFile_Glue_Success(t *testing.T) {
 	}
 }
 
+func TestLicelFile_Glue_BinShift(t *testing.T) {
+	n := 100
+	analogData := make([]float64, n)
+	photonData := make([]float64, n)
+	for i := 0; i < n; i++ {
+		analogData[i] = 2 * float64(100+i)
+		photonData[i] = float64(100 + i)
+	}
+	analogData[25] = 1e6 // выброс в бине 25 попадает в окно только без учёта сдвига
+
+	lf := LicelFile{
+		Profiles: LicelProfilesList{
+			{DeviceID: "BT", Photon: false, Wavelength: 532, BinWidth: 7.5, BinShift: 10, NDataPoints: n, Data: analogData},
+			{DeviceID: "BC", Photon: true, Wavelength: 532, BinWidth: 7.5, BinShift: 10, NDataPoints: n, Data: photonData},
+		},
+	}
+
+	// [150, 300] м → бины [30, 50] вместо [20, 40]
+	got, _, err := lf.Glue(532, 150, 300, "")
+	require.NoError(t, err)
+	assert.Equal(t, analogData[29], got.Data[29])
+	assert.InDelta(t, 2*photonData[51], got.Data[51], 1e-9)
+}
+
 func TestLicelFile_Glue_MissingAnalog(t *testing.T) {
 	lf := LicelFile{
 		Profiles: LicelProfilesList{
 			{DeviceID: "BC", Photon: true, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: make([]float64, 100)},
 		},
 	}
-	_, err := lf.Glue(532, 10, 50, "p")
+	_, _, err := lf.Glue(532, 10, 50, "p")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "analog channel not found")
 }
@@ -369,7 +398,7 @@ func TestLicelFile_Glue_MissingPhoton(t *testing.T) {
 			{DeviceID: "BT", Photon: false, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: make([]float64, 100)},
 		},
 	}
-	_, err := lf.Glue(532, 10, 50, "p")
+	_, _, err := lf.Glue(532, 10, 50, "p")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "photon channel not found")
 }
@@ -381,7 +410,7 @@ func TestLicelFile_Glue_InvalidRange(t *testing.T) {
 			{DeviceID: "BC", Photon: true, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: make([]float64, 100)},
 		},
 	}
-	_, err := lf.Glue(532, 50, 10, "p")
+	_, _, err := lf.Glue(532, 50, 10, "p")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "h1")
 	assert.Contains(t, err.Error(), "must be less than h2")
@@ -394,7 +423,7 @@ func TestLicelFile_Glue_H1OutOfRange(t *testing.T) {
 			{DeviceID: "BC", Photon: true, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: make([]float64, 10)},
 		},
 	}
-	_, err := lf.Glue(532, 200, 300, "p") // h1 maps to idx 26, exceeds dataLen 10
+	_, _, err := lf.Glue(532, 200, 300, "p") // h1 maps to idx 26, exceeds dataLen 10
 	assert.Error(t, err)
 }
 
@@ -405,7 +434,7 @@ func TestLicelFile_Glue_AllZeroPhoton(t *testing.T) {
 			{DeviceID: "BC", Photon: true, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: []float64{0, 0, 0, 0}},
 		},
 	}
-	_, err := lf.Glue(532, 0, 22, "p") // idx2=2, dataLen=4 — попадает в диапазон, но photon=0
+	_, _, err := lf.Glue(532, 0, 22, "p") // idx2=2, dataLen=4 — попадает в диапазон, но photon=0
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "all photon data values are zero")
 }
@@ -418,7 +447,7 @@ func TestLicelFile_Glue_DataLengthMismatch(t *testing.T) {
 			{DeviceID: "BC", Photon: true, Wavelength: 532, Polarization: "p", BinWidth: 7.5, Data: []float64{10, 20, 30}},
 		},
 	}
-	got, err := lf.Glue(532, 0, 15, "p") // idx2=2, fits in 3
+	got, _, err := lf.Glue(532, 0, 15, "p") // idx2=2, fits in 3
 	require.NoError(t, err)
 	assert.Equal(t, 3, len(got.Data))
 }
@@ -430,3 +459,400 @@ func TestLoadLicelFileFromReader_Empty(t *testing.T) {
 	// Will fail because os.Stdin has no data
 	assert.Error(t, err)
 }
+
+// --- LoadLicelHeader ---
+
+func TestLoadLicelHeader_Testdata(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	full, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+
+	hdr, err := LoadLicelHeader(testFile)
+	require.NoError(t, err)
+
+	assert.True(t, hdr.HeaderOnly)
+	assert.False(t, hdr.FileLoaded)
+	assert.Equal(t, full.MeasurementSite, hdr.MeasurementSite)
+	assert.Equal(t, full.MeasurementStartTime, hdr.MeasurementStartTime)
+	assert.Equal(t, full.NDatasets, hdr.NDatasets)
+	require.Len(t, hdr.Profiles, len(full.Profiles))
+
+	raw, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	for i, pr := range hdr.Profiles {
+		assert.Nil(t, pr.Data, "profile %d", i)
+		assert.Equal(t, full.Profiles[i].DataOffset, pr.DataOffset, "profile %d", i)
+
+		// Первый отсчёт по смещению совпадает с полностью загруженным профилем
+		first := bytesToFloat64Array(raw[pr.DataOffset : pr.DataOffset+4])[0] * pr.scaleFactor()
+		assert.Equal(t, full.Profiles[i].Data[0], first, "profile %d", i)
+	}
+
+	last := hdr.Profiles[len(hdr.Profiles)-1]
+	assert.Equal(t, int64(len(raw)), last.DataOffset+int64(last.NDataPoints)*4+2)
+}
+
+func TestLoadLicelHeader_SaveFails(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	hdr, err := LoadLicelHeader(testFile)
+	require.NoError(t, err)
+
+	err = hdr.Save(filepath.Join(t.TempDir(), "out"))
+	assert.Error(t, err)
+}
+
+func TestLicelFile_Save_KeepsTargetOnError(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	orig, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	fname := filepath.Join(dir, "b2021019.223500")
+	require.NoError(t, os.WriteFile(fname, orig, 0o600))
+
+	hdr, err := LoadLicelHeader(fname)
+	require.NoError(t, err)
+	assert.Error(t, hdr.Save(fname))
+
+	lf, err := LoadLicelFile(fname)
+	require.NoError(t, err)
+	lf.Profiles[0].Data[0] = math.NaN()
+	assert.ErrorIs(t, lf.Save(fname), ErrNotRepresentable)
+
+	got, err := os.ReadFile(fname)
+	require.NoError(t, err)
+	assert.Equal(t, orig, got)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file left behind")
+
+	lf.Profiles[0].Data[0] = 0
+	require.NoError(t, lf.Save(fname))
+	fi, err := os.Stat(fname)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+}
+
+func TestLoadLicelHeader_NonExistent(t *testing.T) {
+	_, err := LoadLicelHeader("/nonexistent/file.licel")
+	assert.Error(t, err)
+}
+
+// --- Raw data round-trip ---
+
+func TestLicelFile_WriteTo_DataByteExact(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	orig, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+
+	lf, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+	for i, pr := range lf.Profiles {
+		require.Len(t, pr.Raw, pr.NDataPoints, "profile %d", i)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, "b2021019.223500"))
+	written := buf.Bytes()
+
+	lf2, err := LoadLicelHeaderFromReader(bytes.NewReader(written))
+	require.NoError(t, err)
+	for i, pr := range lf.Profiles {
+		n := int64(pr.NDataPoints) * 4
+		want := orig[pr.DataOffset : pr.DataOffset+n]
+		got := written[lf2.Profiles[i].DataOffset : lf2.Profiles[i].DataOffset+n]
+		assert.True(t, bytes.Equal(want, got), "profile %d binary data differs", i)
+	}
+}
+
+// --- Byte-exact round-trip ---
+
+// makeLicelBytes — собирает LICEL-файл из строк заголовка (без окончаний) и отсчётов профилей
+func makeLicelBytes(header []string, profileLines []string, data [][]int32) []byte {
+	var buf bytes.Buffer
+	for _, l := range header {
+		buf.WriteString(l + "\r\n")
+	}
+	for _, l := range profileLines {
+		buf.WriteString(l + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	for _, d := range data {
+		for _, v := range d {
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		}
+		buf.WriteString("\r\n")
+	}
+	return buf.Bytes()
+}
+
+func TestLicelFile_Save_ByteExact_Testdata(t *testing.T) {
+	entries, err := os.ReadDir(filepath.Join("..", "testdata"))
+	require.NoError(t, err)
+
+	for _, e := range entries {
+		if e.IsDir() || !isValidFilename(e.Name()) {
+			continue
+		}
+		t.Run(e.Name(), func(t *testing.T) {
+			src := filepath.Join("..", "testdata", e.Name())
+			orig, err := os.ReadFile(src)
+			require.NoError(t, err)
+
+			lf, err := LoadLicelFile(src)
+			require.NoError(t, err)
+
+			dst := filepath.Join(t.TempDir(), e.Name())
+			require.NoError(t, lf.Save(dst))
+			saved, err := os.ReadFile(dst)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(orig, saved), "Save(Load(x)) != x")
+
+			var buf bytes.Buffer
+			require.NoError(t, lf.WriteTo(&buf, ""))
+			assert.True(t, bytes.Equal(orig, buf.Bytes()), "WriteTo with FileName != x")
+		})
+	}
+}
+
+func TestLicelFile_WriteTo_PreservesUnusualHeader(t *testing.T) {
+	raw := makeLicelBytes(
+		[]string{
+			" x2020041.120000",
+			" Site 10/02/2020 19:22:35 10/02/2020 19:24:15 120 -070.55 -33.125 5.5",
+			" 0000600 0010 0000000 0000 01 0000000 0000",
+		},
+		[]string{" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0"},
+		[][]int32{{1, 2, 3}},
+	)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	assert.Equal(t, "x2020041.120000", lf.FileName)
+	assert.Equal(t, -70.55, lf.Longitude)
+	assert.Equal(t, -33.125, lf.Latitude)
+
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Equal(t, string(raw), buf.String())
+}
+
+func TestLicelFile_WriteTo_RegeneratesChangedLines(t *testing.T) {
+	raw := makeLicelBytes(
+		[]string{
+			" x2020041.120000",
+			" Site 10/02/2020 19:22:35 10/02/2020 19:24:15 120 -070.55 -33.125 5.5",
+			" 0000600 0010 0000000 0000 01 0000000 0000",
+		},
+		[]string{" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0"},
+		[][]int32{{1, 2, 3}},
+	)
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	lf.Latitude = -33.5
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, "renamed.dat"))
+	lines := strings.SplitAfter(buf.String(), "\n")
+
+	assert.Equal(t, lf.formatFirstLine("renamed.dat"), lines[0])
+	assert.Equal(t, lf.formatSecondLine(time.UTC), lines[1])
+	assert.Contains(t, lines[1], "-033.5")
+	assert.Equal(t, " 0000600 0010 0000000 0000 01 0000000 0000\r\n", lines[2])
+	assert.Equal(t, " 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0\r\n", lines[3])
+
+	lf.Profiles[0].HighVoltage = 900
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	lines = strings.SplitAfter(buf.String(), "\n")
+	assert.Equal(t, " x2020041.120000\r\n", lines[0])
+	assert.Equal(t, lf.Profiles[0].metadata(), lines[3])
+}
+
+// --- Header variants ---
+
+func TestLoadLicelFile_ExtendedVariant(t *testing.T) {
+	raw := makeLicelBytes(
+		[]string{
+			" long_station_file_name_2021.123456",
+			" Mount Site 2 10/02/2020 19:22:35 10/02/2020 19:24:15 0120 0023.7 0037.9 00 0.0 25.3 1013.2",
+			" 0000600 0010 0000300 0010 02 0000000 0000 0000150 0005 7",
+		},
+		[]string{
+			" 1 0 1 00003 1 0000 7.50 00355.o 0 0 00 000 12 000600 0.500 BT0 1 2",
+			" 1 1 4 00002 1 0850 7.50 00355.o 0 0 00 000 00 000150 3.1746 BC10",
+		},
+		[][]int32{{1, 2, 3}, {4, 5}},
+	)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	assert.Equal(t, "long_station_file_name_2021.123456", lf.FileName)
+	assert.Equal(t, "Mount Site 2", lf.MeasurementSite)
+	assert.Equal(t, 120.0, lf.AltitudeAboveSeaLevel)
+	assert.Equal(t, []string{"0.0", "25.3", "1013.2"}, lf.HeaderExtra)
+	assert.Equal(t, VariantLaser4, lf.Variant)
+	assert.Equal(t, 150, lf.Laser4NShots)
+	assert.Equal(t, 5, lf.Laser4Freq)
+	assert.Equal(t, []string{"7"}, lf.LaserExtra)
+	assert.Equal(t, []string{"1", "2"}, lf.Profiles[0].Extra)
+	assert.Equal(t, 4, lf.Profiles[1].LaserType)
+	assert.Equal(t, 10, lf.Profiles[1].NCrate)
+
+	// Без изменений — исходные байты
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Equal(t, string(raw), buf.String())
+
+	// После изменения строки формируются заново в том же варианте
+	lf.Zenith = 10
+	lf.NDatasets = 2
+	lf.Laser1Freq = 20
+	lf.Profiles[0].HighVoltage = 700
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, ""))
+
+	lf2, err := LoadLicelFileFromReader(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Mount Site 2", lf2.MeasurementSite)
+	assert.Equal(t, 10.0, lf2.Zenith)
+	assert.Equal(t, lf.HeaderExtra, lf2.HeaderExtra)
+	assert.Equal(t, VariantLaser4, lf2.Variant)
+	assert.Equal(t, 150, lf2.Laser4NShots)
+	assert.Equal(t, 5, lf2.Laser4Freq)
+	assert.Equal(t, lf.LaserExtra, lf2.LaserExtra)
+	assert.Equal(t, []string{"1", "2"}, lf2.Profiles[0].Extra)
+	assert.Equal(t, 700, lf2.Profiles[0].HighVoltage)
+	assert.Equal(t, lf.Profiles[1].Data, lf2.Profiles[1].Data)
+}
+
+func TestLicelVariant_JSON(t *testing.T) {
+	b, err := json.Marshal(LicelFile{Variant: VariantLaser4})
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"variant":"laser4"`)
+
+	var lf LicelFile
+	require.NoError(t, json.Unmarshal(b, &lf))
+	assert.Equal(t, VariantLaser4, lf.Variant)
+
+	var v LicelVariant
+	assert.Error(t, v.UnmarshalText([]byte("laser5")))
+}
+
+func TestLoadLicelFile_ClassicVariant(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	lf, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, VariantClassic, lf.Variant)
+	assert.Nil(t, lf.HeaderExtra)
+	assert.Nil(t, lf.LaserExtra)
+	assert.NotContains(t, lf.formatThirdLine(), " 0000000 0000 ")
+}
+
+func TestLoadLicelFile_Line2WithoutDate(t *testing.T) {
+	raw := makeLicelBytes(
+		[]string{" f", " Site 2020-02-10 19:22:35 10/02/2020 19:24:15 0120 0023.7 0037.9 00", " 0 0 0 0 00 0 0"},
+		nil, nil,
+	)
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	assert.Error(t, err)
+}
+
+func TestFormatThirdLine_Laser4(t *testing.T) {
+	lf := LicelFile{Variant: VariantLaser4, Laser4NShots: 150, Laser4Freq: 5}
+	assert.Contains(t, lf.formatThirdLine(), " 0000150 0005")
+
+	// Ненулевой лазер 4 не теряется и в классическом варианте
+	lf = LicelFile{Laser4NShots: 1}
+	assert.Contains(t, lf.formatThirdLine(), " 0000001 0000")
+}
+
+// --- Time zones ---
+
+func locationTestBytes() []byte {
+	return makeLicelBytes(
+		[]string{
+			" x2020041.120000",
+			" Site 10/02/2020 23:30:00 11/02/2020 00:10:00 0020 0131.9 0043.1 50",
+			" 0000600 0010 0000000 0000 01 0000000 0000",
+		},
+		[]string{" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0"},
+		[][]int32{{1, 2, 3}},
+	)
+}
+
+func TestLoadLicelFile_DefaultUTC(t *testing.T) {
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(locationTestBytes()))
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, lf.MeasurementStartTime.Location())
+	assert.True(t, lf.MeasurementStartTime.Equal(time.Date(2020, 2, 10, 23, 30, 0, 0, time.UTC)))
+	assert.True(t, lf.MeasurementStopTime.Equal(time.Date(2020, 2, 11, 0, 10, 0, 0, time.UTC)))
+}
+
+func TestLoadLicelFile_WithLocation(t *testing.T) {
+	loc := time.FixedZone("UTC+10", 10*3600)
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(locationTestBytes()), WithLocation(loc))
+	require.NoError(t, err)
+	assert.Equal(t, loc, lf.MeasurementStartTime.Location())
+	assert.True(t, lf.MeasurementStartTime.Equal(time.Date(2020, 2, 10, 13, 30, 0, 0, time.UTC)))
+
+	lf, err = LoadLicelFileFromReader(bytes.NewReader(locationTestBytes()), WithLocation(nil))
+	require.NoError(t, err)
+	assert.Equal(t, time.UTC, lf.MeasurementStartTime.Location())
+}
+
+func TestLicelFile_WriteTo_Location(t *testing.T) {
+	raw := locationTestBytes()
+	loc := time.FixedZone("UTC+10", 10*3600)
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLocation(loc))
+	require.NoError(t, err)
+
+	// Тот же часовой пояс — исходные строки без изменений
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, "", WithWriteLocation(loc)))
+	assert.Equal(t, string(raw), buf.String())
+
+	// По умолчанию — пояс, в котором файл загружен
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Equal(t, string(raw), buf.String())
+
+	// Другой пояс — времена переводятся
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, "", WithWriteLocation(time.UTC)))
+	lines := strings.SplitAfter(buf.String(), "\n")
+	assert.Contains(t, lines[1], " 10/02/2020 13:30:00 10/02/2020 14:10:00 ")
+
+	back, err := LoadLicelFileFromReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.True(t, back.MeasurementStartTime.Equal(lf.MeasurementStartTime))
+	assert.True(t, back.MeasurementStopTime.Equal(lf.MeasurementStopTime))
+}
+
+func TestLicelFile_WriteTo_LoadedLocation_ByteExact(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	orig, err := os.ReadFile(testFile)
+	require.NoError(t, err)
+	lf, err := LoadLicelFile(testFile, WithLocation(time.FixedZone("UTC+10", 10*3600)))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Equal(t, orig, buf.Bytes())
+
+	// Сформированная заново строка 2 тоже пишется в поясе загрузки
+	lf.rawLines[1] = ""
+	buf.Reset()
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Contains(t, strings.SplitAfter(buf.String(), "\n")[1], " 10/02/2020 19:22:35 10/02/2020 19:24:15 ")
+}
+
+func TestLicelFile_WriteTo_IgnoresProcessLocal(t *testing.T) {
+	lf := LicelFile{
+		MeasurementSite:      "Test",
+		MeasurementStartTime: time.Date(2020, 2, 10, 19, 22, 35, 0, time.FixedZone("X", -5*3600)),
+		MeasurementStopTime:  time.Date(2020, 2, 10, 19, 24, 15, 0, time.FixedZone("X", -5*3600)),
+	}
+	s := lf.formatSecondLine(time.UTC)
+	assert.Contains(t, s, " 11/02/2020 00:22:35 11/02/2020 00:24:15 ")
+}
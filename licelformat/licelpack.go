@@ -5,11 +5,11 @@ import (
 	"bytes"
 	"compress/flate"
 	"fmt"
-	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"time"
 )
 
@@ -27,8 +27,8 @@ func isValidFilename(filename string) bool {
 	return licelFilenameRegex.MatchString(filename)
 }
 
-// NewLicelPack — загружает файлы по glob-маске
-func NewLicelPack(mask string) (*LicelPack, error) {
+// NewLicelPack — загружает файлы по glob-маске; opts применяются к каждому файлу
+func NewLicelPack(mask string, opts ...LoadOption) (*LicelPack, error) {
 	pack := &LicelPack{
 		Data: make(map[string]LicelFile),
 	}
@@ -38,7 +38,7 @@ func NewLicelPack(mask string) (*LicelPack, error) {
 	}
 
 	for _, fname := range files {
-		lf, err := LoadLicelFile(fname)
+		lf, err := LoadLicelFile(fname, opts...)
 		if err != nil {
 			return nil, fmt.Errorf("loading %q: %w", fname, err)
 		}
@@ -60,8 +60,8 @@ func NewLicelPack(mask string) (*LicelPack, error) {
 	return pack, nil
 }
 
-// NewLicelPackFromZip — загружает файлы из zip-архива
-func NewLicelPackFromZip(zipPath string) (*LicelPack, error) {
+// NewLicelPackFromZip — загружает файлы из zip-архива; opts применяются к каждому файлу
+func NewLicelPackFromZip(zipPath string, opts ...LoadOption) (*LicelPack, error) {
 	pack := &LicelPack{
 		Data: make(map[string]LicelFile),
 	}
@@ -71,10 +71,14 @@ func NewLicelPackFromZip(zipPath string) (*LicelPack, error) {
 	}
 	defer zr.Close()
 
+	maxSize := newLoadOptions(opts).Limits.MaxFileSize
 	for _, f := range zr.File {
 		if !isValidFilename(f.Name) {
 			continue
 		}
+		if maxSize >= 0 && f.UncompressedSize64 > uint64(maxSize) {
+			return nil, fmt.Errorf("reading %q from zip: %d bytes: %w", f.Name, f.UncompressedSize64, ErrLimitExceeded)
+		}
 
 		rc, err := f.Open()
 		if err != nil {
@@ -87,7 +91,7 @@ func NewLicelPackFromZip(zipPath string) (*LicelPack, error) {
 			return nil, fmt.Errorf("reading %q from zip: %w", f.Name, err)
 		}
 
-		lFile, err := LoadLicelFileFromReader(bytes.NewReader(fileContent))
+		lFile, err := LoadLicelFileFromReader(bytes.NewReader(fileContent), opts...)
 		if err != nil {
 			return nil, fmt.Errorf("parsing %q from zip: %w", f.Name, err)
 		}
@@ -215,33 +219,83 @@ func (lp *LicelPack) SelectProfiles(isPhoton bool, wavelength float64, polarizat
 }
 
 // Glue склеивает аналоговый и цифровой каналы для каждого файла в паке.
-// Для каждого файла вызывается LicelFile.Glue, и если ошибок нет,
-// полученный склеенный профиль добавляется в Profiles этого файла.
-func (lp *LicelPack) Glue(wvl float64, h1, h2 float64, polarization string) error {
-	for fname, lf := range lp.Data {
-		glued, err := lf.Glue(wvl, h1, h2, polarization)
+// Для каждого файла вызывается LicelFile.Glue, и если ошибок нет ни в одном файле,
+// полученные склеенные профили добавляются в Profiles файлов; при ошибке пак не изменяется.
+// Возвращает результаты склейки по именам файлов (для контроля коэффициентов).
+func (lp *LicelPack) Glue(wvl float64, h1, h2 float64, polarization string) (map[string]GlueResult, error) {
+	return lp.glueFiles(func(lf *LicelFile) (LicelProfile, GlueResult, error) {
+		return lf.Glue(wvl, h1, h2, polarization)
+	})
+}
+
+// GlueWith — склеивает каналы во всех файлах пака с параметрами opts (см. LicelFile.GlueWith)
+// и добавляет или заменяет склеенный профиль так же, как Glue. Возвращает коэффициенты
+// и качество склейки по именам файлов.
+func (lp *LicelPack) GlueWith(wvl float64, polarization string, opts GlueOptions) (map[string]GlueResult, error) {
+	return lp.glueFiles(func(lf *LicelFile) (LicelProfile, GlueResult, error) {
+		return lf.GlueWith(wvl, polarization, opts)
+	})
+}
+
+// glueFiles — склеивает каналы каждого файла функцией glue и добавляет склеенные профили
+// через updateFiles, то есть только если склейка удалась во всех файлах
+func (lp *LicelPack) glueFiles(glue func(lf *LicelFile) (LicelProfile, GlueResult, error)) (map[string]GlueResult, error) {
+	results := make(map[string]GlueResult, len(lp.Data))
+	err := lp.updateFiles(func(fname string, lf *LicelFile) ([]func(), error) {
+		glued, res, err := glue(lf)
 		if err != nil {
-			return fmt.Errorf("%s: %w", fname, err)
+			return nil, err
 		}
+		results[fname] = res
+		return []func(){func() { lf.putGlued(glued) }}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
 
-		// Ищем существующий склеенный профиль для той же длины волны и поляризации
-		replaced := false
-		for i, p := range lf.Profiles {
-			if p.IsGlued() && p.Wavelength == wvl && p.Polarization == polarization {
-				lf.Profiles[i] = glued
-				replaced = true
-				break
-			}
+// putGlued — заменяет склеенный профиль той же длины волны и поляризации или добавляет новый
+func (lf *LicelFile) putGlued(glued LicelProfile) {
+	for i, p := range lf.Profiles {
+		if p.IsGlued() && p.Wavelength == glued.Wavelength && p.Polarization == glued.Polarization {
+			lf.Profiles[i] = glued
+			return
 		}
-		if !replaced {
-			lf.Profiles = append(lf.Profiles, glued)
+	}
+	lf.Profiles = append(lf.Profiles, glued)
+	lf.NDatasets = len(lf.Profiles)
+}
+
+// updateFiles — готовит изменения каждого файла пака функцией prepare и применяет их,
+// только если prepare не вернула ошибку ни для одного файла: при ошибке пак не изменяется.
+// Подготовленные функции изменяют копию файла, переданную в prepare; после применения
+// копии записываются в lp.Data.
+func (lp *LicelPack) updateFiles(prepare func(fname string, lf *LicelFile) ([]func(), error)) error {
+	var updates []func()
+	files := make(map[string]*LicelFile, len(lp.Data))
+	for fname, licf := range lp.Data {
+		u, err := prepare(fname, &licf)
+		if err != nil {
+			return fmt.Errorf("%s: %w", fname, err)
 		}
-		lf.NDatasets = len(lf.Profiles)
-		lp.Data[fname] = lf
+		updates = append(updates, u...)
+		files[fname] = &licf
+	}
+	applyUpdates(updates)
+	for fname, licf := range files {
+		lp.Data[fname] = *licf
 	}
 	return nil
 }
 
+// applyUpdates — применяет подготовленные изменения по порядку
+func applyUpdates(updates []func()) {
+	for _, apply := range updates {
+		apply()
+	}
+}
+
 // SetMaxDist обрезает все профили во всех файлах пака до дальности alt (метры).
 func (lp *LicelPack) SetMaxDist(alt float64) error {
 	for fname, licf := range lp.Data {
@@ -254,9 +308,9 @@ func (lp *LicelPack) SetMaxDist(alt float64) error {
 }
 
 // Save — сохраняет все файлы LicelPack на диск
-func (lp *LicelPack) Save() error {
+func (lp *LicelPack) Save(opts ...WriteOption) error {
 	for fname, licf := range lp.Data {
-		if err := licf.Save(fname); err != nil {
+		if err := licf.Save(fname, opts...); err != nil {
 			return fmt.Errorf("saving %q: %w", fname, err)
 		}
 	}
@@ -265,7 +319,7 @@ func (lp *LicelPack) Save() error {
 
 // SaveToZip — сохраняет все файлы LicelPack в zip-архив.
 // Уровень сжатия задаётся полем ZipCompressionLevel: 0 — deflate по умолчанию, 1–9 — степень deflate.
-func (lp *LicelPack) SaveToZip(zipPath string) error {
+func (lp *LicelPack) SaveToZip(zipPath string, opts ...WriteOption) error {
 	file, err := os.Create(zipPath)
 	if err != nil {
 		return fmt.Errorf("creating zip %q: %w", zipPath, err)
@@ -273,75 +327,62 @@ func (lp *LicelPack) SaveToZip(zipPath string) error {
 	defer file.Close()
 
 	zw := zip.NewWriter(file)
-	defer zw.Close()
+	if lp.ZipCompressionLevel > 0 && lp.ZipCompressionLevel <= 9 {
+		level := lp.ZipCompressionLevel
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
 
+	// Записи формируются потоково: файл не сериализуется в память целиком
 	for fname, licf := range lp.Data {
 		entryName := filepath.Base(fname)
-
-		if lp.ZipCompressionLevel > 0 && lp.ZipCompressionLevel <= 9 {
-			if err := writeCompressedEntry(zw, entryName, lp.ZipCompressionLevel, &licf); err != nil {
-				return err
-			}
-		} else {
-			w, err := zw.Create(entryName)
-			if err != nil {
-				return fmt.Errorf("creating zip entry %q: %w", entryName, err)
-			}
-			if err := licf.WriteTo(w, entryName); err != nil {
-				return fmt.Errorf("writing %q to zip: %w", entryName, err)
-			}
+		fh := &zip.FileHeader{Name: entryName, Method: zip.Deflate}
+		fh.SetMode(0644)
+		w, err := zw.CreateHeader(fh)
+		if err != nil {
+			return fmt.Errorf("creating zip entry %q: %w", entryName, err)
+		}
+		if err := licf.WriteTo(w, entryName, opts...); err != nil {
+			return fmt.Errorf("writing %q to zip: %w", entryName, err)
 		}
 	}
 
-	return nil
+	return zw.Close()
 }
 
-// writeCompressedEntry — создаёт zip-запись с указанным уровнем deflate.
-func writeCompressedEntry(zw *zip.Writer, name string, level int, licf *LicelFile) error {
-	// Сериализуем несжатые данные для вычисления CRC и размеров.
-	var rawBuf bytes.Buffer
-	if err := licf.WriteTo(&rawBuf, name); err != nil {
-		return fmt.Errorf("serializing %q: %w", name, err)
-	}
-	raw := rawBuf.Bytes()
-
-	// Сжимаем в буфер.
-	var compBuf bytes.Buffer
-	fw, err := flate.NewWriter(&compBuf, level)
-	if err != nil {
-		return fmt.Errorf("creating deflate writer for %q: %w", name, err)
+// Merge добавляет в пак все файлы из other. Файлы с совпадающими именами заменяются файлами из other.
+// StartTime/StopTime пересчитываются по объединённому набору.
+func (lp *LicelPack) Merge(other *LicelPack) {
+	if lp.Data == nil {
+		lp.Data = make(map[string]LicelFile, len(other.Data))
 	}
-	if _, err := fw.Write(raw); err != nil {
-		fw.Close()
-		return fmt.Errorf("compressing %q: %w", name, err)
-	}
-	if err := fw.Close(); err != nil {
-		return fmt.Errorf("closing deflate writer for %q: %w", name, err)
-	}
-	compressed := compBuf.Bytes()
-
-	h := crc32.NewIEEE()
-	if _, err := h.Write(raw); err != nil {
-		return fmt.Errorf("calculating crc32 for %q: %w", name, err)
-	}
-
-	fh := &zip.FileHeader{
-		Name:               name,
-		Method:             zip.Deflate,
-		CRC32:              h.Sum32(),
-		UncompressedSize64: uint64(len(raw)),
-		CompressedSize64:   uint64(len(compressed)),
+	for fname, lf := range other.Data {
+		lp.Data[fname] = lf
 	}
-	fh.SetMode(0644)
+	lp.StartTime, lp.StopTime = timeBounds(lp.Data)
+}
 
-	w, err := zw.CreateRaw(fh)
-	if err != nil {
-		return fmt.Errorf("creating raw zip entry %q: %w", name, err)
+// Names — имена файлов пака (ключи Data) в лексикографическом порядке
+func (lp *LicelPack) Names() []string {
+	names := make([]string, 0, len(lp.Data))
+	for fname := range lp.Data {
+		names = append(names, fname)
 	}
+	sort.Strings(names)
+	return names
+}
 
-	if _, err := w.Write(compressed); err != nil {
-		return fmt.Errorf("writing compressed data for %q: %w", name, err)
+// timeBounds — минимальное время начала и максимальное время окончания среди файлов
+func timeBounds(data map[string]LicelFile) (time.Time, time.Time) {
+	var minStart, maxStop time.Time
+	for _, lf := range data {
+		if minStart.IsZero() || lf.MeasurementStartTime.Before(minStart) {
+			minStart = lf.MeasurementStartTime
+		}
+		if lf.MeasurementStopTime.After(maxStop) {
+			maxStop = lf.MeasurementStopTime
+		}
 	}
-
-	return nil
+	return minStart, maxStop
 }
@@ -3,6 +3,7 @@ package licelformat
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
@@ -10,6 +11,33 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// testPackNames — имена файлов пака testPack
+var testPackNames = []string{"/data/a", "/data/b"}
+
+// testPack — пак из двух файлов с независимыми копиями профилей profiles
+func testPack(profiles ...LicelProfile) LicelPack {
+	pack := LicelPack{Data: make(map[string]LicelFile, len(testPackNames))}
+	for _, name := range testPackNames {
+		prs := make(LicelProfilesList, len(profiles))
+		for i, pr := range profiles {
+			pr.Data = slices.Clone(pr.Data)
+			pr.Raw = slices.Clone(pr.Raw)
+			prs[i] = pr
+		}
+		pack.Data[name] = LicelFile{MeasurementSite: "Site", NDatasets: len(prs), Profiles: prs}
+	}
+	return pack
+}
+
+// eachFile — проверяет каждый файл пака в подтесте с именем файла
+func eachFile(t *testing.T, pack LicelPack, check func(t *testing.T, lf LicelFile)) {
+	t.Helper()
+	require.NotEmpty(t, pack.Data)
+	for _, name := range pack.Names() {
+		t.Run(name, func(t *testing.T) { check(t, pack.Data[name]) })
+	}
+}
+
 func TestIsValidFilename(t *testing.T) {
 	assert.True(t, isValidFilename("b2021019.223500"))
 	assert.True(t, isValidFilename("b0000000.000000"))
@@ -531,7 +559,7 @@ func TestLicelPack_Glue_Success(t *testing.T) {
 		},
 	}
 
-	err := lp.Glue(532, 15, 45, "p")
+	_, err := lp.Glue(532, 15, 45, "p")
 	require.NoError(t, err)
 
 	f1 := lp.Data["f1"]
@@ -563,8 +591,12 @@ func TestLicelPack_Glue_MultipleFiles(t *testing.T) {
 		},
 	}
 
-	err := lp.Glue(532, 0, 22, "p") // idx2=2 для f1 (max=2.99), idx2=2 для f2 (max=2.99)
+	results, err := lp.Glue(532, 0, 22, "p") // idx2=2 для f1 (max=2.99), idx2=2 для f2 (max=2.99)
 	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.InDelta(t, 10, results["f1"].K, 1e-12)
+	assert.Len(t, results["f1"].Weights, 10)
+	assert.Len(t, results["f2"].Weights, 4)
 
 	assert.Equal(t, 3, len(lp.Data["f1"].Profiles))
 	assert.Equal(t, 3, lp.Data["f1"].NDatasets)
@@ -584,7 +616,7 @@ func TestLicelPack_Glue_Error(t *testing.T) {
 		},
 	}
 
-	err := lp.Glue(532, 0, 15, "p")
+	_, err := lp.Glue(532, 0, 15, "p")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "f1")
 	assert.Contains(t, err.Error(), "photon channel not found")
@@ -592,7 +624,7 @@ func TestLicelPack_Glue_Error(t *testing.T) {
 
 func TestLicelPack_Glue_EmptyPack(t *testing.T) {
 	lp := &LicelPack{Data: map[string]LicelFile{}}
-	err := lp.Glue(532, 0, 15, "p")
+	_, err := lp.Glue(532, 0, 15, "p")
 	assert.NoError(t, err)
 }
 
@@ -618,7 +650,7 @@ func TestLicelPack_Glue_ReplacesExisting(t *testing.T) {
 		},
 	}
 
-	err := lp.Glue(532, 15, 45, "p")
+	_, err := lp.Glue(532, 15, 45, "p")
 	require.NoError(t, err)
 	require.Equal(t, 3, len(lp.Data["f1"].Profiles))
 	require.Equal(t, "BG", lp.Data["f1"].Profiles[2].DeviceID)
@@ -626,7 +658,7 @@ func TestLicelPack_Glue_ReplacesExisting(t *testing.T) {
 	oldBG := lp.Data["f1"].Profiles[2]
 
 	// Второй вызов — заменяет, а не добавляет четвёртый профиль
-	err = lp.Glue(532, 30, 60, "p")
+	_, err = lp.Glue(532, 30, 60, "p")
 	require.NoError(t, err)
 	assert.Equal(t, 3, len(lp.Data["f1"].Profiles), "должен быть 3 профиля, а не 4")
 	assert.Equal(t, 3, lp.Data["f1"].NDatasets)
@@ -679,3 +711,38 @@ func TestLicelPack_SaveToZip_CompressionLevels(t *testing.T) {
 		})
 	}
 }
+
+// --- Merge ---
+
+func TestLicelPack_Names(t *testing.T) {
+	pack := testPack()
+	assert.Equal(t, testPackNames, pack.Names())
+	assert.Empty(t, (&LicelPack{}).Names())
+}
+
+func TestLicelPack_Merge(t *testing.T) {
+	t0 := time.Date(2020, 2, 10, 19, 0, 0, 0, time.UTC)
+	lp := &LicelPack{
+		Data: map[string]LicelFile{
+			"f1": {MeasurementStartTime: t0, MeasurementStopTime: t0.Add(time.Minute), MeasurementSite: "old"},
+		},
+	}
+	other := &LicelPack{
+		Data: map[string]LicelFile{
+			"f1": {MeasurementStartTime: t0, MeasurementStopTime: t0.Add(time.Minute), MeasurementSite: "new"},
+			"f2": {MeasurementStartTime: t0.Add(-time.Hour), MeasurementStopTime: t0.Add(2 * time.Hour)},
+		},
+	}
+
+	lp.Merge(other)
+	assert.Len(t, lp.Data, 2)
+	assert.Equal(t, "new", lp.Data["f1"].MeasurementSite)
+	assert.Equal(t, t0.Add(-time.Hour), lp.StartTime)
+	assert.Equal(t, t0.Add(2*time.Hour), lp.StopTime)
+}
+
+func TestLicelPack_Merge_NilData(t *testing.T) {
+	lp := &LicelPack{}
+	lp.Merge(&LicelPack{Data: map[string]LicelFile{"f1": {}}})
+	assert.Len(t, lp.Data, 1)
+}
@@ -1,9 +1,11 @@
 package licelformat
 
 import (
-	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 )
 
@@ -16,109 +18,126 @@ type LicelProfilesList []LicelProfile
 
 // LicelProfile — структура, представляющая измерительный канал
 type LicelProfile struct {
-	Active       bool                    `json:"is_active"`
-	Photon       bool                    `json:"is_photon"`     // Активность канала и тип измерения (фотоны или нет)
-	LaserType    int                     `json:"laser_type"`    // Тип лазера
-	NDataPoints  int                     `json:"data_points"`   // Количество данных
-	Reserved     [LICEL_MAX_RESERVED]int `json:"reserved"`      // Резервные значения
-	HighVoltage  int                     `json:"high_voltage"`  // Напряжение
-	BinWidth     float64                 `json:"bin_width"`     // Ширина бина
-	Wavelength   float64                 `json:"wavelength"`    // Длина волны
-	Polarization string                  `json:"polarization"`  // Поляризация
-	BinShift     int                     `json:"bin_shift"`     // Сдвиг бина
-	DecBinShift  int                     `json:"dec_bin_shift"` // Децибельный сдвиг
-	AdcBits      int                     `json:"adc_bits"`      // Биты АЦП
-	NShots       int                     `json:"n_shots"`       // Количество импульсов
-	DiscrLevel   float64                 `json:"discr_level"`   // Уровень дискриминации
-	DeviceID     string                  `json:"device_id"`     // Идентификатор устройства
-	NCrate       int                     `json:"n_crate"`       // Номер устройства в крэйте
-	Data         []float64               `json:"data"`          // Данные
+	Active        bool                    `json:"is_active"`
+	Photon        bool                    `json:"is_photon"`                 // Активность канала и тип измерения (фотоны или нет)
+	LaserType     int                     `json:"laser_type"`                // Тип лазера
+	NDataPoints   int                     `json:"data_points"`               // Количество данных
+	Reserved      [LICEL_MAX_RESERVED]int `json:"reserved"`                  // Резервные значения
+	HighVoltage   int                     `json:"high_voltage"`              // Напряжение
+	BinWidth      float64                 `json:"bin_width"`                 // Ширина бина
+	Wavelength    float64                 `json:"wavelength"`                // Длина волны
+	Polarization  string                  `json:"polarization"`              // Поляризация
+	BinShift      int                     `json:"bin_shift"`                 // Сдвиг нулевой дальности, целые бины
+	DecBinShift   int                     `json:"dec_bin_shift"`             // Дробная часть сдвига, тысячные бина
+	AdcBits       int                     `json:"adc_bits"`                  // Биты АЦП
+	NShots        int                     `json:"n_shots"`                   // Количество импульсов
+	DiscrLevel    float64                 `json:"discr_level"`               // Уровень дискриминации
+	DeviceID      string                  `json:"device_id"`                 // Идентификатор устройства
+	NCrate        int                     `json:"n_crate"`                   // Номер устройства в крэйте
+	Extra         []string                `json:"extra,omitempty"`           // Дополнительные поля строки заголовка после DeviceID
+	DataOffset    int64                   `json:"data_offset"`               // Смещение бинарных данных профиля в файле (байт)
+	Truncated     bool                    `json:"truncated,omitempty"`       // Данные обрезаны: файл кончился раньше (WithLenient)
+	DeadTime      float64                 `json:"dead_time,omitempty"`       // Применённая поправка на мёртвое время, нс
+	DeadTimeModel DeadTimeModel           `json:"dead_time_model,omitempty"` // Модель поправки на мёртвое время (DeadTimeNone — не применялась)
+	Background    Background              `json:"background,omitzero"`       // Вычтенный фон (Method == BackgroundNone — не вычитался)
+	Data          []float64               `json:"data"`                      // Данные
+	Raw           []int32                 `json:"-"`                         // Исходные отсчёты АЦП/счётчика фотонов (до масштабирования)
+
+	rawLine string // исходная строка заголовка профиля (с окончанием строки)
+	rawKey  string // значения полей на момент загрузки, см. headerKey
 }
 
 // newLicelProfile — parse string line into LicelProfile
 func newLicelProfile(line string) (LicelProfile, error) {
 	items := strings.Fields(line)
 	if len(items) < 16 {
-		return LicelProfile{}, fmt.Errorf("profile header: expected at least 16 fields, got %d", len(items))
+		return LicelProfile{}, headerError("profile header: expected at least 16 fields, got %d", len(items))
 	}
 
 	wvlpol := strings.SplitN(items[7], ".", 2)
 	if len(wvlpol) != 2 {
-		return LicelProfile{}, fmt.Errorf("profile header: invalid wavelength.polarization format %q", items[7])
+		return LicelProfile{}, fieldError("wavelength.polarization", items[7], errors.New("expected <wavelength>.<polarization>"))
 	}
 
 	wavelength, err := str2Float(wvlpol[0])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing wavelength %q: %w", wvlpol[0], err)
+		return LicelProfile{}, fieldError("wavelength", wvlpol[0], err)
 	}
 
 	active, err := str2Bool(items[0])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing active %q: %w", items[0], err)
+		return LicelProfile{}, fieldError("active", items[0], err)
 	}
 	photon, err := str2Bool(items[1])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing photon %q: %w", items[1], err)
+		return LicelProfile{}, fieldError("photon", items[1], err)
 	}
 	laserType, err := str2Int(items[2])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing laser type %q: %w", items[2], err)
+		return LicelProfile{}, fieldError("laser type", items[2], err)
 	}
 	nDataPoints, err := str2Int(items[3])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing data points %q: %w", items[3], err)
+		return LicelProfile{}, fieldError("data points", items[3], err)
+	}
+	if nDataPoints < 0 {
+		return LicelProfile{}, fieldError("data points", items[3], errors.New("must not be negative"))
 	}
 	reserved0, err := str2Int(items[4])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing reserved[0] %q: %w", items[4], err)
+		return LicelProfile{}, fieldError("reserved[0]", items[4], err)
 	}
 	highVoltage, err := str2Int(items[5])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing high voltage %q: %w", items[5], err)
+		return LicelProfile{}, fieldError("high voltage", items[5], err)
 	}
 	binWidth, err := str2Float(items[6])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing bin width %q: %w", items[6], err)
+		return LicelProfile{}, fieldError("bin width", items[6], err)
 	}
 	reserved1, err := str2Int(items[8])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing reserved[1] %q: %w", items[8], err)
+		return LicelProfile{}, fieldError("reserved[1]", items[8], err)
 	}
 	reserved2, err := str2Int(items[9])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing reserved[2] %q: %w", items[9], err)
+		return LicelProfile{}, fieldError("reserved[2]", items[9], err)
 	}
 	binShift, err := str2Int(items[10])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing bin shift %q: %w", items[10], err)
+		return LicelProfile{}, fieldError("bin shift", items[10], err)
 	}
 	decBinShift, err := str2Int(items[11])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing dec bin shift %q: %w", items[11], err)
+		return LicelProfile{}, fieldError("dec bin shift", items[11], err)
 	}
 	adcBits, err := str2Int(items[12])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing adc bits %q: %w", items[12], err)
+		return LicelProfile{}, fieldError("adc bits", items[12], err)
 	}
 	nShots, err := str2Int(items[13])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing n shots %q: %w", items[13], err)
+		return LicelProfile{}, fieldError("n shots", items[13], err)
 	}
 	discrLevel, err := str2Float(items[14])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing discr level %q: %w", items[14], err)
+		return LicelProfile{}, fieldError("discr level", items[14], err)
 	}
 
 	if len(items[15]) < 3 {
-		return LicelProfile{}, fmt.Errorf("profile header: device field %q too short", items[15])
+		return LicelProfile{}, fieldError("device", items[15], errors.New("expected device ID and crate number"))
 	}
 	deviceID := items[15][:2]
+	var extra []string
+	if len(items) > 16 {
+		extra = items[16:]
+	}
 	nCrate, err := str2Int(items[15][2:])
 	if err != nil {
-		return LicelProfile{}, fmt.Errorf("parsing n crate %q: %w", items[15][2:], err)
+		return LicelProfile{}, fieldError("n crate", items[15][2:], err)
 	}
 
-	return LicelProfile{
+	lp := LicelProfile{
 		Active:       active,
 		Photon:       photon,
 		LaserType:    laserType,
@@ -135,7 +154,22 @@ func newLicelProfile(line string) (LicelProfile, error) {
 		DiscrLevel:   discrLevel,
 		DeviceID:     deviceID,
 		NCrate:       nCrate,
-	}, nil
+		Extra:        extra,
+	}
+	// Отсчёты профиля с данными переводятся в Data масштабом, поэтому он должен быть определён
+	if nDataPoints > 0 {
+		if nShots <= 0 {
+			return LicelProfile{}, fieldError("n shots", items[13], errors.New("must be positive for a profile with data"))
+		}
+		if scale := lp.scaleFactor(); scale == 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+			field, token := "discr level", items[14]
+			if photon {
+				field, token = "bin width", items[6]
+			}
+			return LicelProfile{}, fieldError(field, token, fmt.Errorf("scale factor %g of a profile with data is not usable", scale))
+		}
+	}
+	return lp, nil
 }
 
 // metadata — возвращает строку с метаданными канала для записи в заголовок файла
@@ -154,61 +188,184 @@ func (lp *LicelProfile) metadata() string {
 			lp.Reserved[1], lp.Reserved[2], lp.BinShift, lp.DecBinShift,
 			lp.AdcBits, lp.NShots, lp.DiscrLevel, lp.DeviceID, lp.NCrate)
 	}
+	if len(lp.Extra) > 0 {
+		s += " " + strings.Join(lp.Extra, " ")
+	}
 	return fmt.Sprintf("%-78s\r\n", s)
 }
 
-// scaleFactor вычисляет масштабирующий коэффициент для данных профиля
+// metadataLine — исходная строка заголовка профиля, если его поля не менялись после загрузки,
+// иначе — строка, сформированная metadata
+func (lp *LicelProfile) metadataLine() string {
+	if lp.rawLine != "" && lp.rawKey == lp.headerKey() {
+		return lp.rawLine
+	}
+	return lp.metadata()
+}
+
+// headerKey — значения полей заголовка профиля для обнаружения изменений
+func (lp *LicelProfile) headerKey() string {
+	return fmt.Sprintf("%t|%t|%d|%d|%v|%d|%v|%v|%s|%d|%d|%d|%d|%v|%s|%d|%q",
+		lp.Active, lp.Photon, lp.LaserType, lp.NDataPoints, lp.Reserved,
+		lp.HighVoltage, lp.BinWidth, lp.Wavelength, lp.Polarization,
+		lp.BinShift, lp.DecBinShift, lp.AdcBits, lp.NShots, lp.DiscrLevel,
+		lp.DeviceID, lp.NCrate, lp.Extra)
+}
+
+// scaleFactor вычисляет масштабирующий коэффициент для данных профиля:
+// мВ на отсчёт для аналоговых каналов, МГц на отсчёт для фотонных (см. Units)
 func (lp *LicelProfile) scaleFactor() float64 {
 	if lp.Photon {
-		return 1.0 / (float64(lp.NShots) * 0.05)
+		return 1.0 / (float64(lp.NShots) * lp.binTime())
+	}
+	return lp.DiscrLevel * 1000.0 / (math.Ldexp(1, lp.AdcBits) * float64(lp.NShots))
+}
+
+// decodeSamples — декодирует len(data) отсчётов little-endian int32 из b за один проход:
+// исходные отсчёты записываются в raw (если raw не nil), масштабированные — в data.
+// Память не выделяется; b должен содержать не меньше 4*len(data) байт.
+func decodeSamples(data []float64, raw []int32, b []byte, scale float64) {
+	b = b[:4*len(data)]
+	if raw == nil {
+		for i := range data {
+			data[i] = float64(int32(binary.LittleEndian.Uint32(b[4*i:]))) * scale
+		}
+		return
+	}
+	raw = raw[:len(data)]
+	for i := range data {
+		v := int32(binary.LittleEndian.Uint32(b[4*i:]))
+		raw[i] = v
+		data[i] = float64(v) * scale
 	}
-	adcScale := 1 << lp.AdcBits
-	return lp.DiscrLevel * 1000.0 / float64(adcScale*lp.NShots)
 }
 
 // profileRaw — возвращает unscaled бинарное представление данных канала
+// с параметрами записи по умолчанию, см. appendRaw
 func (lp *LicelProfile) profileRaw() ([]byte, error) {
-	scale := lp.scaleFactor()
-	unscaled := make([]float64, len(lp.Data))
+	o := newWriteOptions(nil)
+	b, _, err := lp.appendRaw(nil, &o)
+	return b, err
+}
+
+// appendRaw — дописывает к dst unscaled бинарное представление данных канала (little-endian int32).
+// Отсчёты, не изменившиеся после загрузки (Data[i] совпадает с Raw[i]*scale),
+// берутся из Raw без пересчёта, поэтому нетронутые данные записываются байт в байт.
+// Остальные округляются и проверяются по правилам o (см. countConverter); при
+// достаточной ёмкости dst память не выделяется.
+func (lp *LicelProfile) appendRaw(dst []byte, o *WriteOptions) ([]byte, ClampReport, error) {
+	// пустому профилю масштаб не нужен: при 0 импульсов он не определён
+	if len(lp.Data) == 0 {
+		return dst, ClampReport{}, nil
+	}
+	c, err := newCountConverter(lp, o)
+	if err != nil {
+		return dst, ClampReport{}, err
+	}
+	useRaw := len(lp.Raw) == len(lp.Data)
+	off := len(dst)
+	dst = slices.Grow(dst, 4*len(lp.Data))[:off+4*len(lp.Data)]
+	out := dst[off:]
 	for i, v := range lp.Data {
-		unscaled[i] = v / scale
+		var n int32
+		if useRaw && v == float64(lp.Raw[i])*c.scale {
+			n = lp.Raw[i]
+		} else if n, err = c.count(i, v); err != nil {
+			return dst[:off], c.report, err
+		}
+		binary.LittleEndian.PutUint32(out[4*i:], uint32(n))
 	}
-	return float64toInt32Bytes(unscaled)
+	return dst, c.report, nil
 }
 
-// float64toInt32Bytes — преобразование массива float64 в []byte (little-endian int32)
-func float64toInt32Bytes(data []float64) ([]byte, error) {
-	buf := new(bytes.Buffer)
-	for _, num := range data {
-		if err := binary.Write(buf, binary.LittleEndian, int32(num)); err != nil {
-			return nil, err
-		}
+// resizeFloat64 — s длины n, переиспользуя ёмкость s
+func resizeFloat64(s []float64, n int) []float64 {
+	if cap(s) >= n {
+		return s[:n]
+	}
+	return make([]float64, n)
+}
+
+// resizeInt32 — s длины n, переиспользуя ёмкость s
+func resizeInt32(s []int32, n int) []int32 {
+	if cap(s) >= n {
+		return s[:n]
 	}
-	return buf.Bytes(), nil
+	return make([]int32, n)
 }
 
+// Идентификаторы устройств (первые два символа последнего поля заголовка профиля)
+const (
+	DeviceIDAnalog     = "BT" // аналоговый канал транзиентного рекордера
+	DeviceIDPhoton     = "BC" // канал счёта фотонов
+	DeviceIDGlued      = "BG" // склеенный канал (Glue)
+	DeviceIDPhotodiode = "PD" // фотодиод (энергия импульса)
+	DeviceIDPowerMeter = "PM" // измеритель мощности
+
+	DeviceIDRangeCorrected = "RC" // производный профиль P(r)·r² (RangeCorrected), не записывается рекордером
+)
+
 // IsPhoton возвращает true, если профиль является фотонным каналом (DeviceID == "BC").
 func (lp *LicelProfile) IsPhoton() bool {
-	return lp.DeviceID == "BC"
+	return lp.DeviceID == DeviceIDPhoton
 }
 
 // IsAnalog возвращает true, если профиль является аналоговым каналом (DeviceID == "BT").
 func (lp *LicelProfile) IsAnalog() bool {
-	return lp.DeviceID == "BT"
+	return lp.DeviceID == DeviceIDAnalog
 }
 
 // IsGlued возвращает true, если профиль является склеенным каналом (DeviceID == "BG").
 func (lp *LicelProfile) IsGlued() bool {
-	return lp.DeviceID == "BG"
+	return lp.DeviceID == DeviceIDGlued
+}
+
+// ZeroBin — положение нулевой дальности в бинах: BinShift + DecBinShift/1000.
+// Бины до него записаны до выстрела лазера (задержка запуска рекордера).
+func (lp *LicelProfile) ZeroBin() float64 {
+	return float64(lp.BinShift) + float64(lp.DecBinShift)/1000
 }
 
-// SetMaxDist обрезает данные профиля до дальности alt (метры).
-// idx = alt / BinWidth. Ошибка если idx ≤ 0 или idx > NDataPoints.
+// Ranges — дальность начала каждого из NDataPoints бинов, метры: (i − ZeroBin)·BinWidth.
+// Бины до нулевой дальности получают отрицательные значения.
+func (lp *LicelProfile) Ranges() []float64 {
+	zero := lp.ZeroBin()
+	r := make([]float64, lp.NDataPoints)
+	for i := range r {
+		r[i] = (float64(i) - zero) * lp.BinWidth
+	}
+	return r
+}
+
+// Altitudes — высота начала каждого бина над уровнем моря, метры:
+// AltitudeAboveSeaLevel + дальность·cos(Zenith) по заголовку файла lf.
+func (lp *LicelProfile) Altitudes(lf *LicelFile) []float64 {
+	h := lp.Ranges()
+	cosZ := math.Cos(lf.Zenith * math.Pi / 180)
+	for i := range h {
+		h[i] = lf.AltitudeAboveSeaLevel + h[i]*cosZ
+	}
+	return h
+}
+
+// RangeIndex — индекс бина, содержащего дальность r (метры), с учётом ZeroBin.
+// Может быть отрицательным или ≥ NDataPoints; BinWidth должна быть положительной.
+func (lp *LicelProfile) RangeIndex(r float64) int {
+	return int(math.Floor(r/lp.BinWidth + lp.ZeroBin()))
+}
+
+// IsRangeCorrected возвращает true, если профиль получен RangeCorrected (DeviceID == "RC").
+func (lp *LicelProfile) IsRangeCorrected() bool {
+	return lp.DeviceID == DeviceIDRangeCorrected
+}
+
+// SetMaxDist обрезает данные профиля до дальности alt (метры): остаются бины до
+// idx = RangeIndex(alt). Ошибка если idx ≤ 0 или idx > NDataPoints.
 func (lp *LicelProfile) SetMaxDist(alt float64) error {
 	if lp.BinWidth <= 0 {
 		return fmt.Errorf("SetMaxDist: bin width must be positive, got %.2f", lp.BinWidth)
 	}
-	idx := int(alt / lp.BinWidth)
+	idx := lp.RangeIndex(alt)
 	if idx <= 0 {
 		return fmt.Errorf("SetMaxDist: alt %.0f m → idx %d, must be > 0", alt, idx)
 	}
@@ -216,6 +373,9 @@ func (lp *LicelProfile) SetMaxDist(alt float64) error {
 		return fmt.Errorf("SetMaxDist: alt %.0f m → idx %d exceeds NDataPoints %d", alt, idx, lp.NDataPoints)
 	}
 	lp.Data = lp.Data[:idx]
+	if len(lp.Raw) > idx {
+		lp.Raw = lp.Raw[:idx]
+	}
 	lp.NDataPoints = len(lp.Data)
 	return nil
 }
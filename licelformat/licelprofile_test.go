@@ -166,6 +166,51 @@ func TestLicelProfile_SetMaxDist_ZeroBinWidth(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestLicelProfile_SetMaxDist_BinShift(t *testing.T) {
+	// нулевая дальность в бине 10: дальности 375 м соответствует бин 60
+	pr := LicelProfile{BinWidth: 7.5, BinShift: 10, NDataPoints: 100, Data: make([]float64, 100)}
+	require.NoError(t, pr.SetMaxDist(375))
+	assert.Equal(t, 60, pr.NDataPoints)
+	assert.Len(t, pr.Data, 60)
+}
+
+// --- Ranges / Altitudes ---
+
+func TestLicelProfile_ZeroBin(t *testing.T) {
+	assert.Equal(t, 0.0, (&LicelProfile{}).ZeroBin())
+	assert.InDelta(t, 2.25, (&LicelProfile{BinShift: 2, DecBinShift: 250}).ZeroBin(), 1e-12)
+}
+
+func TestLicelProfile_Ranges(t *testing.T) {
+	pr := LicelProfile{BinWidth: 7.5, NDataPoints: 4}
+	assert.Equal(t, []float64{0, 7.5, 15, 22.5}, pr.Ranges())
+
+	pr.BinShift, pr.DecBinShift = 1, 500
+	assert.InDeltaSlice(t, []float64{-11.25, -3.75, 3.75, 11.25}, pr.Ranges(), 1e-12)
+}
+
+func TestLicelProfile_Altitudes(t *testing.T) {
+	pr := LicelProfile{BinWidth: 10, BinShift: 1, NDataPoints: 3}
+	lf := LicelFile{AltitudeAboveSeaLevel: 100, Zenith: 60}
+	assert.InDeltaSlice(t, []float64{95, 100, 105}, pr.Altitudes(&lf), 1e-9)
+
+	lf.Zenith = 0
+	assert.InDeltaSlice(t, []float64{90, 100, 110}, pr.Altitudes(&lf), 1e-9)
+}
+
+func TestLicelProfile_RangeIndex(t *testing.T) {
+	pr := LicelProfile{BinWidth: 7.5}
+	assert.Equal(t, 0, pr.RangeIndex(0))
+	assert.Equal(t, 0, pr.RangeIndex(7.4))
+	assert.Equal(t, 1, pr.RangeIndex(7.5))
+	assert.Equal(t, -1, pr.RangeIndex(-1))
+
+	pr.BinShift, pr.DecBinShift = 3, 500
+	assert.Equal(t, 3, pr.RangeIndex(0))
+	assert.Equal(t, 4, pr.RangeIndex(3.75))
+	assert.Equal(t, 2, pr.RangeIndex(-4))
+}
+
 // --- IsPhoton / IsAnalog / IsGlued ---
 
 func TestLicelProfile_IsPhoton_True(t *testing.T) {
@@ -210,3 +255,76 @@ func TestBtoi(t *testing.T) {
 	assert.Equal(t, 1, btoi(true))
 	assert.Equal(t, 0, btoi(false))
 }
+
+// --- Raw ---
+
+// decodeData — заполняет Raw отсчётами из буфера b (little-endian int32), а Data —
+// масштабированными значениями, как загрузчик
+func (lp *LicelProfile) decodeData(b []byte) {
+	n := len(b) / 4
+	lp.Raw = make([]int32, n)
+	lp.Data = make([]float64, n)
+	decodeSamples(lp.Data, lp.Raw, b, lp.scaleFactor())
+}
+
+func TestLicelProfile_DecodeData_KeepsRaw(t *testing.T) {
+	pr := LicelProfile{Photon: true, NShots: 1000}
+	pr.decodeData([]byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff})
+	assert.Equal(t, []int32{1, -1}, pr.Raw)
+	assert.Equal(t, []float64{pr.scaleFactor(), -pr.scaleFactor()}, pr.Data)
+}
+
+func TestLicelProfile_ProfileRaw_PrefersRaw(t *testing.T) {
+	pr := LicelProfile{AdcBits: 12, NShots: 2001, DiscrLevel: 0.5}
+	pr.decodeData([]byte{7, 0, 0, 0, 9, 0, 0, 0, 11, 0, 0, 0})
+
+	data, err := pr.profileRaw()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{7, 0, 0, 0, 9, 0, 0, 0, 11, 0, 0, 0}, data)
+
+	// Изменённый отсчёт пересчитывается из Data, остальные берутся из Raw
+	pr.Data[1] = 100 * pr.scaleFactor()
+	data, err = pr.profileRaw()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{7, 0, 0, 0, 100, 0, 0, 0, 11, 0, 0, 0}, data)
+}
+
+func TestLicelProfile_SetMaxDist_TruncatesRaw(t *testing.T) {
+	pr := LicelProfile{BinWidth: 7.5, NDataPoints: 4}
+	pr.decodeData(make([]byte, 16))
+	require.NoError(t, pr.SetMaxDist(15))
+	assert.Len(t, pr.Raw, 2)
+	assert.Len(t, pr.Data, 2)
+}
+
+func TestNewLicelProfile_ExtraFields(t *testing.T) {
+	pr, err := newLicelProfile(" 1 0 1 16380 1 0000 7.50 00355.o 0 0 00 000 12 002001 0.500 BT0 42 x")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"42", "x"}, pr.Extra)
+	assert.Contains(t, pr.metadata(), "BT0 42 x")
+}
+
+func TestDecodeSamples(t *testing.T) {
+	b := []byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 2, 0, 0, 0, 9}
+	data := make([]float64, 3)
+	raw := make([]int32, 3)
+	decodeSamples(data, raw, b, 0.5)
+	assert.Equal(t, []int32{1, -1, 2}, raw)
+	assert.Equal(t, []float64{0.5, -0.5, 1}, data)
+
+	decodeSamples(data[:2], nil, b, 2)
+	assert.Equal(t, []float64{2, -2, 1}, data)
+}
+
+func TestLicelProfile_AppendRaw_ReusesBuffer(t *testing.T) {
+	pr := LicelProfile{Photon: true, NShots: 20}
+	pr.decodeData([]byte{7, 0, 0, 0, 9, 0, 0, 0})
+	pr.Data[1] = 10 // изменённый отсчёт кодируется через масштаб
+
+	buf := make([]byte, 2, 64)
+	o := newWriteOptions(nil)
+	out, _, err := pr.appendRaw(buf, &o)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 7, 0, 0, 0, 10, 0, 0, 0}, out)
+	assert.Same(t, &buf[0], &out[0])
+}
@@ -0,0 +1,161 @@
+package licelformat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// LicelReader — ленивое чтение LICEL-файла с произвольным доступом к профилям.
+// При создании разбирается только заголовок; данные профиля читаются по его
+// DataOffset и масштабируются только при запросе.
+type LicelReader struct {
+	r          io.ReaderAt
+	closer     io.Closer
+	header     LicelFile
+	discardRaw bool // LoadOptions.DiscardRaw
+}
+
+// NewLicelReader — создаёт LicelReader поверх io.ReaderAt и разбирает заголовок.
+// ProfileFilter из opts ограничивает список доступных профилей.
+func NewLicelReader(r io.ReaderAt, opts ...LoadOption) (*LicelReader, error) {
+	o := newLoadOptions(opts)
+	o.HeaderOnly = true
+	header, err := loadFromReader(bufio.NewReader(io.NewSectionReader(r, 0, math.MaxInt64)), o)
+	if err != nil {
+		return nil, err
+	}
+	return &LicelReader{r: r, header: header, discardRaw: o.DiscardRaw}, nil
+}
+
+// OpenLicelReader — открывает LICEL-файл по имени для чтения отдельных профилей.
+// Файл должен быть закрыт вызовом Close.
+func OpenLicelReader(fname string, opts ...LoadOption) (*LicelReader, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, fmt.Errorf("opening file %q: %w", fname, err)
+	}
+	lr, err := NewLicelReader(f, opts...)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("reading header of %q: %w", fname, err)
+	}
+	lr.closer = f
+	return lr, nil
+}
+
+// Close — закрывает файл, открытый OpenLicelReader. Для NewLicelReader ничего не делает.
+func (lr *LicelReader) Close() error {
+	if lr.closer == nil {
+		return nil
+	}
+	return lr.closer.Close()
+}
+
+// Header — возвращает заголовок файла (профили без данных)
+func (lr *LicelReader) Header() LicelFile {
+	header := lr.header
+	header.Profiles = make(LicelProfilesList, len(lr.header.Profiles))
+	copy(header.Profiles, lr.header.Profiles)
+	return header
+}
+
+// NProfiles — количество профилей в файле
+func (lr *LicelReader) NProfiles() int {
+	return len(lr.header.Profiles)
+}
+
+// ReadProfile — читает и масштабирует данные профиля с индексом i
+func (lr *LicelReader) ReadProfile(i int) (LicelProfile, error) {
+	if i < 0 || i >= len(lr.header.Profiles) {
+		return LicelProfile{}, fmt.Errorf("profile index %d out of range [0, %d)", i, len(lr.header.Profiles))
+	}
+	pr := lr.header.Profiles[i]
+	if err := lr.readData(&pr, i); err != nil {
+		return LicelProfile{}, err
+	}
+	return pr, nil
+}
+
+// SelectProfile — читает профиль с заданными типом, длиной волны и поляризацией.
+// Правила выбора совпадают с LicelFile.SelectProfile; "" в polarization — любая поляризация.
+func (lr *LicelReader) SelectProfile(isPhoton bool, wavelength float64, polarization string) (LicelProfile, bool, error) {
+	pr, ok := lr.header.SelectProfile(isPhoton, wavelength, polarization)
+	if !ok {
+		return LicelProfile{}, false, nil
+	}
+	idx := -1
+	for i := range lr.header.Profiles {
+		if lr.header.Profiles[i].DataOffset == pr.DataOffset {
+			idx = i
+			break
+		}
+	}
+	if err := lr.readData(&pr, idx); err != nil {
+		return LicelProfile{}, true, err
+	}
+	return pr, true, nil
+}
+
+// ReadProfileInto — как ReadProfile, но записывает профиль в pr, переиспользуя ёмкость
+// pr.Data и pr.Raw. При обходе профилей тысяч файлов с одним pr данные декодируются
+// без выделения памяти.
+func (lr *LicelReader) ReadProfileInto(i int, pr *LicelProfile) error {
+	if i < 0 || i >= len(lr.header.Profiles) {
+		return fmt.Errorf("profile index %d out of range [0, %d)", i, len(lr.header.Profiles))
+	}
+	data, raw := pr.Data, pr.Raw
+	*pr = lr.header.Profiles[i]
+	pr.Data = resizeFloat64(data, pr.NDataPoints)
+	if !lr.discardRaw {
+		pr.Raw = resizeInt32(raw, pr.NDataPoints)
+	}
+	return lr.readInto(pr, i)
+}
+
+// readData — выделяет Data (и Raw) профиля и заполняет их, см. readInto
+func (lr *LicelReader) readData(pr *LicelProfile, idx int) error {
+	pr.Data = make([]float64, pr.NDataPoints)
+	if !lr.discardRaw {
+		pr.Raw = make([]int32, pr.NDataPoints)
+	}
+	return lr.readInto(pr, idx)
+}
+
+// readChunkSize — размер блока, которым данные профиля читаются через ReadAt
+const readChunkSize = 4096
+
+// chunkPool — буферы блоков ReadAt; пул делает чтение безопасным для горутин и не выделяет память
+var chunkPool = sync.Pool{New: func() any { return new([readChunkSize]byte) }}
+
+// readInto — читает len(pr.Data) отсчётов по DataOffset блоками и декодирует их в Data и Raw.
+// Ошибка возвращается как *ParseError с индексом профиля idx.
+func (lr *LicelReader) readInto(pr *LicelProfile, idx int) error {
+	chunk := chunkPool.Get().(*[readChunkSize]byte)
+	defer chunkPool.Put(chunk)
+
+	scale := pr.scaleFactor()
+	off := pr.DataOffset
+	for n := 0; n < len(pr.Data); {
+		m := min(len(pr.Data)-n, readChunkSize/4)
+		b := chunk[:4*m]
+		got, err := lr.r.ReadAt(b, off)
+		if got < len(b) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return dataError(pr.DataOffset, idx, err)
+		}
+		var raw []int32
+		if pr.Raw != nil {
+			raw = pr.Raw[n : n+m]
+		}
+		decodeSamples(pr.Data[n:n+m], raw, b, scale)
+		n += m
+		off += int64(len(b))
+	}
+	return nil
+}
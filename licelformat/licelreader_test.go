@@ -0,0 +1,115 @@
+package licelformat
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicelReader_ReadProfile(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	full, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+
+	lr, err := OpenLicelReader(testFile)
+	require.NoError(t, err)
+	defer lr.Close()
+
+	require.Equal(t, full.NDatasets, lr.NProfiles())
+	assert.Equal(t, full.MeasurementSite, lr.Header().MeasurementSite)
+
+	for i := range full.Profiles {
+		pr, err := lr.ReadProfile(i)
+		require.NoError(t, err, "profile %d", i)
+		assert.Equal(t, full.Profiles[i].Data, pr.Data, "profile %d", i)
+	}
+}
+
+func TestLicelReader_SelectProfile(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	full, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	lr, err := NewLicelReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	pr, ok, err := lr.SelectProfile(true, 532, "p")
+	require.NoError(t, err)
+	require.True(t, ok)
+	want, _ := full.SelectProfile(true, 532, "p")
+	assert.Equal(t, want.Data, pr.Data)
+
+	_, ok, err = lr.SelectProfile(true, 999, "")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLicelReader_HeaderDoesNotAlias(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	lr, err := NewLicelReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	h := lr.Header()
+	h.Profiles[0].NDataPoints = 1
+	pr, err := lr.ReadProfile(0)
+	require.NoError(t, err)
+	assert.Equal(t, 16380, len(pr.Data))
+}
+
+func TestLicelReader_Errors(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+
+	lr, err := NewLicelReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	_, err = lr.ReadProfile(-1)
+	assert.Error(t, err)
+	_, err = lr.ReadProfile(lr.NProfiles())
+	assert.Error(t, err)
+
+	// Обрезанный файл: заголовок читается, последний профиль — нет
+	short, err := NewLicelReader(bytes.NewReader(raw[:len(raw)-100]))
+	require.NoError(t, err)
+	_, err = short.ReadProfile(short.NProfiles() - 1)
+	assert.Error(t, err)
+	_, err = short.ReadProfile(0)
+	assert.NoError(t, err)
+
+	_, err = OpenLicelReader("/nonexistent/file.licel")
+	assert.Error(t, err)
+}
+
+func TestLicelReader_ReadProfileInto(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	full, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+
+	lr, err := OpenLicelReader(testFile)
+	require.NoError(t, err)
+	defer lr.Close()
+
+	var pr LicelProfile
+	require.NoError(t, lr.ReadProfileInto(0, &pr))
+	data := &pr.Data[0]
+	for i := range full.Profiles {
+		require.NoError(t, lr.ReadProfileInto(i, &pr), "profile %d", i)
+		assert.Equal(t, full.Profiles[i].Data, pr.Data, "profile %d", i)
+		assert.Equal(t, full.Profiles[i].Raw, pr.Raw, "profile %d", i)
+		assert.Equal(t, full.Profiles[i].DeviceID, pr.DeviceID, "profile %d", i)
+	}
+	// Ёмкость Data переиспользуется
+	assert.Same(t, data, &pr.Data[0])
+
+	allocs := testing.AllocsPerRun(10, func() {
+		_ = lr.ReadProfileInto(3, &pr)
+	})
+	assert.Zero(t, allocs)
+
+	assert.Error(t, lr.ReadProfileInto(len(full.Profiles), &pr))
+}
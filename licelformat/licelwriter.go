@@ -0,0 +1,158 @@
+package licelformat
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// LicelWriter — потоковая запись LICEL-файла. Заголовок (строки 1–3 и заголовки профилей)
+// записывается при создании, затем данные профилей принимаются по одному каналу в порядке
+// заголовков. В памяти хранятся только заголовки и данные текущего профиля, поэтому
+// программы сбора данных и zip-архивы могут формировать файлы ограниченным объёмом памяти.
+type LicelWriter struct {
+	bw       *bufio.Writer
+	profiles LicelProfilesList // заголовки профилей без данных
+	next     int               // индекс профиля, данные которого ожидаются
+	err      error             // первая ошибка записи; после неё запись невозможна
+	buf      []byte            // буфер кодирования, переиспользуется между профилями
+	opts     WriteOptions
+}
+
+// NewLicelWriter — записывает заголовок header в w и возвращает LicelWriter для данных профилей.
+// Данные профилей header не используются: подойдёт и файл, загруженный WithHeaderOnly.
+// NDatasets должно совпадать с числом профилей.
+// fname записывается в первую строку; пустая строка означает header.FileName.
+// Времена записываются в поясе header.MeasurementStartTime (для загруженного файла — в поясе
+// WithLocation, по умолчанию UTC), если не задано WithWriteLocation.
+func NewLicelWriter(w io.Writer, header *LicelFile, fname string, opts ...WriteOption) (*LicelWriter, error) {
+	if header.NDatasets != len(header.Profiles) {
+		return nil, fmt.Errorf("NDatasets is %d, but there are %d profiles", header.NDatasets, len(header.Profiles))
+	}
+	if fname == "" {
+		fname = header.FileName
+	}
+	o := newWriteOptions(opts)
+	if o.Location == nil {
+		o.Location = header.MeasurementStartTime.Location()
+	}
+	lw := &LicelWriter{
+		bw:       bufio.NewWriter(w),
+		profiles: make(LicelProfilesList, len(header.Profiles)),
+		opts:     o,
+	}
+	for i, p := range header.Profiles {
+		p.Data, p.Raw = nil, nil
+		lw.profiles[i] = p
+	}
+
+	if _, err := lw.bw.WriteString(header.headerLine(0, fname, header.formatFirstLine(fname))); err != nil {
+		return nil, fmt.Errorf("writing line 1: %w", err)
+	}
+	if _, err := lw.bw.WriteString(header.headerLine(1, header.secondLineKey(o.Location), header.formatSecondLine(o.Location))); err != nil {
+		return nil, fmt.Errorf("writing line 2: %w", err)
+	}
+	if _, err := lw.bw.WriteString(header.headerLine(2, header.thirdLineKey(), header.formatThirdLine())); err != nil {
+		return nil, fmt.Errorf("writing line 3: %w", err)
+	}
+	for i := range header.Profiles {
+		if _, err := lw.bw.WriteString(header.Profiles[i].metadataLine()); err != nil {
+			return nil, fmt.Errorf("writing metadata for profile %d: %w", i, err)
+		}
+	}
+	if _, err := lw.bw.WriteString("\r\n"); err != nil {
+		return nil, fmt.Errorf("writing header/body separator: %w", err)
+	}
+	return lw, nil
+}
+
+// Next — заголовок профиля, данные которого ожидаются следующими; false — все данные записаны
+func (lw *LicelWriter) Next() (LicelProfile, bool) {
+	if lw.next >= len(lw.profiles) {
+		return LicelProfile{}, false
+	}
+	return lw.profiles[lw.next], true
+}
+
+// WriteData — записывает масштабированные данные очередного профиля.
+// Значения переводятся в отсчёты масштабом из заголовка профиля;
+// len(data) должно совпадать с его NDataPoints.
+func (lw *LicelWriter) WriteData(data []float64) error {
+	pr, err := lw.nextProfile(len(data))
+	if err != nil {
+		return err
+	}
+	pr.Data = data
+	return lw.writeProfile(&pr)
+}
+
+// WriteRaw — записывает исходные отсчёты АЦП/счётчика фотонов очередного профиля без масштабирования.
+// len(counts) должно совпадать с NDataPoints профиля.
+func (lw *LicelWriter) WriteRaw(counts []int32) error {
+	if _, err := lw.nextProfile(len(counts)); err != nil {
+		return err
+	}
+	lw.buf = slices.Grow(lw.buf[:0], 4*len(counts))[:4*len(counts)]
+	for i, v := range counts {
+		binary.LittleEndian.PutUint32(lw.buf[4*i:], uint32(v))
+	}
+	return lw.writeBlock(lw.buf)
+}
+
+// writeProfile — записывает данные профиля lp, предпочитая нетронутые отсчёты из Raw (см. profileRaw)
+func (lw *LicelWriter) writeProfile(lp *LicelProfile) error {
+	var report ClampReport
+	var err error
+	lw.buf, report, err = lp.appendRaw(lw.buf[:0], &lw.opts)
+	if err != nil {
+		return fmt.Errorf("serializing profile %d: %w", lw.next, err)
+	}
+	if report.Total() > 0 {
+		report.Profile = lw.next
+		lw.opts.OnClamp(report)
+	}
+	return lw.writeBlock(lw.buf)
+}
+
+// nextProfile — заголовок очередного профиля с проверкой числа отсчётов n
+func (lw *LicelWriter) nextProfile(n int) (LicelProfile, error) {
+	if lw.err != nil {
+		return LicelProfile{}, lw.err
+	}
+	pr, ok := lw.Next()
+	if !ok {
+		return LicelProfile{}, fmt.Errorf("all %d profiles already written", len(lw.profiles))
+	}
+	if n != pr.NDataPoints {
+		return LicelProfile{}, fmt.Errorf("profile %d: header declares %d data points, got %d", lw.next, pr.NDataPoints, n)
+	}
+	return pr, nil
+}
+
+// writeBlock — записывает бинарные данные очередного профиля и CRLF после них
+func (lw *LicelWriter) writeBlock(data []byte) error {
+	if _, err := lw.bw.Write(data); err != nil {
+		lw.err = fmt.Errorf("writing binary data for profile %d: %w", lw.next, err)
+		return lw.err
+	}
+	if _, err := lw.bw.WriteString("\r\n"); err != nil {
+		lw.err = fmt.Errorf("writing post-profile %d CRLF: %w", lw.next, err)
+		return lw.err
+	}
+	lw.next++
+	return nil
+}
+
+// Close — проверяет, что записаны данные всех профилей, и сбрасывает буфер.
+// Нижележащий io.Writer не закрывается.
+func (lw *LicelWriter) Close() error {
+	if lw.err != nil {
+		return lw.err
+	}
+	if lw.next < len(lw.profiles) {
+		return fmt.Errorf("data written for %d of %d profiles", lw.next, len(lw.profiles))
+	}
+	return lw.bw.Flush()
+}
@@ -0,0 +1,82 @@
+package licelformat
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicelWriter_ByteExact_Testdata(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	full, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	// Заголовок без данных, данные профилей подаются по одному
+	hdr, err := LoadLicelHeaderFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	lw, err := NewLicelWriter(&buf, &hdr, "")
+	require.NoError(t, err)
+	for i := range full.Profiles {
+		next, ok := lw.Next()
+		require.True(t, ok)
+		assert.Equal(t, full.Profiles[i].Wavelength, next.Wavelength)
+		require.NoError(t, lw.WriteRaw(full.Profiles[i].Raw))
+	}
+	_, ok := lw.Next()
+	assert.False(t, ok)
+	require.NoError(t, lw.Close())
+	assert.Equal(t, raw, buf.Bytes())
+}
+
+func TestLicelWriter_WriteData(t *testing.T) {
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes("02")))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	lw, err := NewLicelWriter(&buf, &lf, "")
+	require.NoError(t, err)
+	for _, pr := range lf.Profiles {
+		require.NoError(t, lw.WriteData(pr.Data))
+	}
+	require.NoError(t, lw.Close())
+
+	lf2, err := LoadLicelFileFromReader(&buf)
+	require.NoError(t, err)
+	for i := range lf.Profiles {
+		assert.Equal(t, lf.Profiles[i].Raw, lf2.Profiles[i].Raw)
+	}
+}
+
+func TestLicelWriter_Errors(t *testing.T) {
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes("02")))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	lw, err := NewLicelWriter(&buf, &lf, "")
+	require.NoError(t, err)
+	assert.ErrorContains(t, lw.WriteRaw([]int32{1, 2}), "header declares 3 data points, got 2")
+	require.NoError(t, lw.WriteRaw([]int32{1, 2, 3}))
+	assert.ErrorContains(t, lw.Close(), "data written for 1 of 2 profiles")
+	require.NoError(t, lw.WriteRaw([]int32{4, 5, 6}))
+	assert.ErrorContains(t, lw.WriteRaw([]int32{7, 8, 9}), "all 2 profiles already written")
+	require.NoError(t, lw.Close())
+
+	lf.NDatasets = 3
+	_, err = NewLicelWriter(&buf, &lf, "")
+	assert.ErrorContains(t, err, "NDatasets is 3, but there are 2 profiles")
+}
+
+func TestLicelFile_WriteTo_DataLengthMismatch(t *testing.T) {
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes("02")))
+	require.NoError(t, err)
+	lf.Profiles[1].Data = lf.Profiles[1].Data[:2]
+
+	var buf bytes.Buffer
+	assert.ErrorContains(t, lf.WriteTo(&buf, ""), "profile 1: header declares 3 data points, got 2")
+}
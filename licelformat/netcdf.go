@@ -47,8 +47,10 @@ func (ab *attrBuilder) build() (api.AttributeMap, error) {
 //	Profile vars: file_index, wavelength, polarization, bin_width, nshots,
 //	              device_id, is_photon, discr_level, adc_bits, active,
 //	              laser_type, high_voltage, bin_shift, dec_bin_shift,
-//	              n_crate, npoints
-//	Data:         signal (profile × range, float64, NaN-padded)
+//	              n_crate, npoints, units, dead_time, dead_time_model,
+//	              background_{method,from,to,level,slope,std,bins}
+//	Data:         signal (profile × range, float64, NaN-padded; units attr
+//	              is mV, MHz or "mixed" — then see the units variable)
 func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 	nfiles := len(lp.Data)
 	if nfiles == 0 {
@@ -162,6 +164,16 @@ func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 	reserved0 := make([]int32, nprofiles)
 	reserved1 := make([]int32, nprofiles)
 	reserved2 := make([]int32, nprofiles)
+	units := make([]string, nprofiles)
+	deadTimes := make([]float64, nprofiles)
+	deadTimeModels := make([]int32, nprofiles)
+	bgMethods := make([]int32, nprofiles)
+	bgFrom := make([]float64, nprofiles)
+	bgTo := make([]float64, nprofiles)
+	bgLevels := make([]float64, nprofiles)
+	bgSlopes := make([]float64, nprofiles)
+	bgStds := make([]float64, nprofiles)
+	bgBins := make([]int32, nprofiles)
 
 	for j, fe := range flat {
 		fileIdxs[j] = int32(fe.fileIdx)
@@ -183,6 +195,23 @@ func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 		reserved0[j] = int32(fe.profile.Reserved[0])
 		reserved1[j] = int32(fe.profile.Reserved[1])
 		reserved2[j] = int32(fe.profile.Reserved[2])
+		units[j] = string(fe.profile.Units())
+		deadTimes[j] = fe.profile.DeadTime
+		deadTimeModels[j] = int32(fe.profile.DeadTimeModel)
+		bg := fe.profile.Background
+		bgMethods[j] = int32(bg.Method)
+		bgFrom[j], bgTo[j] = bg.From, bg.To
+		bgLevels[j], bgSlopes[j], bgStds[j] = bg.Level, bg.Slope, bg.Std
+		bgBins[j] = int32(bg.Bins)
+	}
+
+	// Единица signal общая, если у всех профилей она одна; иначе см. переменную units
+	signalUnits := units[0]
+	for _, u := range units[1:] {
+		if u != signalUnits {
+			signalUnits = "mixed"
+			break
+		}
 	}
 
 	// --- Signal: 2D NaN-padded ---
@@ -284,7 +313,7 @@ func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 		return err
 	}
 	if err := addIntVarWithFlags(cw, "is_photon", isPhotons, dimProfile,
-		"photon counting channel flag", "", int32(-1)); err != nil {
+		"photon counting channel flag", "", int32(-1), "0, 1", "analog photon_counting"); err != nil {
 		return err
 	}
 	if err := addFloatVar(cw, "discr_level", discrLevels, dimProfile, "discriminator level", "millivolts", math.NaN()); err != nil {
@@ -323,6 +352,38 @@ func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 	if err := addIntVar(cw, "npoints", npoints, dimProfile, "number of valid data points", "", int32(-1)); err != nil {
 		return err
 	}
+	if err := addStrVar(cw, "units", units, dimProfile, "units of the signal row"); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "dead_time", deadTimes, dimProfile, "applied dead-time correction", "nanoseconds", math.NaN()); err != nil {
+		return err
+	}
+	if err := addIntVarWithFlags(cw, "dead_time_model", deadTimeModels, dimProfile,
+		"dead-time correction model", "", int32(-1), "0, 1, 2", "none non_paralyzable paralyzable"); err != nil {
+		return err
+	}
+	if err := addIntVarWithFlags(cw, "background_method", bgMethods, dimProfile,
+		"subtracted background estimate", "", int32(-1), "0, 1, 2, 3", "none mean median linear"); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "background_from", bgFrom, dimProfile, "start of the background window", "meters", math.NaN()); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "background_to", bgTo, dimProfile, "end of the background window (0 = last bin)", "meters", math.NaN()); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "background_level", bgLevels, dimProfile, "subtracted background at zero range", "", math.NaN()); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "background_slope", bgSlopes, dimProfile, "subtracted background slope per meter", "", math.NaN()); err != nil {
+		return err
+	}
+	if err := addFloatVar(cw, "background_std", bgStds, dimProfile, "standard deviation of the background window", "", math.NaN()); err != nil {
+		return err
+	}
+	if err := addIntVar(cw, "background_bins", bgBins, dimProfile, "number of bins in the background estimate", "", int32(-1)); err != nil {
+		return err
+	}
 
 	// ── Range coordinate ──────────────────────────────────────────────────────
 
@@ -332,7 +393,7 @@ func (lp *LicelPack) SaveToNetCDF3(fname string) error {
 
 	// ── Signal (2D) ───────────────────────────────────────────────────────────
 
-	attrs, err := newAttrs().add("long_name", "lidar signal").add("units", "millivolts").add("FillValue", math.NaN()).add("cell_methods", "range: mean").build()
+	attrs, err := newAttrs().add("long_name", "lidar signal").add("units", signalUnits).add("FillValue", math.NaN()).add("cell_methods", "range: mean").build()
 	if err != nil {
 		return fmt.Errorf("signal attrs: %w", err)
 	}
@@ -408,14 +469,14 @@ func addFloatVarWithCalendar(cw api.Writer, name string, values []float64, dims
 	})
 }
 
-func addIntVarWithFlags(cw api.Writer, name string, values []int32, dims []string, longName, units string, fillValue int32) error {
+func addIntVarWithFlags(cw api.Writer, name string, values []int32, dims []string, longName, units string, fillValue int32, flagValues, flagMeanings string) error {
 	ab := newAttrs().add("long_name", longName)
 	if units != "" {
 		ab = ab.add("units", units)
 	}
 	ab = ab.add("FillValue", fillValue)
-	ab = ab.add("flag_values", "0, 1")
-	ab = ab.add("flag_meanings", "analog photon_counting")
+	ab = ab.add("flag_values", flagValues)
+	ab = ab.add("flag_meanings", flagMeanings)
 	attrs, err := ab.build()
 	if err != nil {
 		return fmt.Errorf("%s attrs: %w", name, err)
@@ -429,7 +490,11 @@ func addIntVarWithFlags(cw api.Writer, name string, values []int32, dims []strin
 
 // ─── LoadLicelPackFromNetCDF3 – loads from NetCDF3 (CDF) ─────────────────────
 
-func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
+// LoadLicelPackFromNetCDF3 — загружает пак из NetCDF3. Из opts учитываются Location
+// (пояс, в который переводятся времена), HeaderOnly и ProfileFilter.
+func LoadLicelPackFromNetCDF3(fname string, opts ...LoadOption) (*LicelPack, error) {
+	o := newLoadOptions(opts)
+
 	nc, err := netcdf.Open(fname)
 	if err != nil {
 		return nil, fmt.Errorf("opening netcdf3 file: %w", err)
@@ -474,6 +539,15 @@ func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
 	deviceIDs := readStrings(nc, "device_id", int(nprofiles))
 	isPhotons := readInt32s(nc, "is_photon", int(nprofiles))
 	discrLevels := readFloat64s(nc, "discr_level", int(nprofiles))
+	deadTimes := readFloat64s(nc, "dead_time", int(nprofiles))
+	deadTimeModels := readInt32s(nc, "dead_time_model", int(nprofiles))
+	bgMethods := readInt32s(nc, "background_method", int(nprofiles))
+	bgFrom := readFloat64s(nc, "background_from", int(nprofiles))
+	bgTo := readFloat64s(nc, "background_to", int(nprofiles))
+	bgLevels := readFloat64s(nc, "background_level", int(nprofiles))
+	bgSlopes := readFloat64s(nc, "background_slope", int(nprofiles))
+	bgStds := readFloat64s(nc, "background_std", int(nprofiles))
+	bgBins := readInt32s(nc, "background_bins", int(nprofiles))
 	adcBits := readInt32s(nc, "adc_bits", int(nprofiles))
 	actives := readInt32s(nc, "active", int(nprofiles))
 	laserTypes := readInt32s(nc, "laser_type", int(nprofiles))
@@ -495,6 +569,12 @@ func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
 	if !ok {
 		return nil, fmt.Errorf("signal variable is not [][]float64, got %T", signalVar.Values)
 	}
+	// Файлы прежних версий (units = "millivolts") хранят фотонные каналы в масштабе
+	// 1/(NShots·0.05 мкс) независимо от BinWidth; такие строки пересчитываются в МГц
+	var legacyUnits bool
+	if u, ok := signalVar.Attributes.Get("units"); ok {
+		legacyUnits = u == "millivolts"
+	}
 
 	// --- Build LicelPack ---
 	fileMap := make(map[int32]*LicelFile)
@@ -502,8 +582,8 @@ func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
 
 	for fi := int32(0); fi < int32(nfiles); fi++ {
 		i := int(fi)
-		fts := time.Unix(int64(startTimes[i]), 0)
-		fto := time.Unix(int64(stopTimes[i]), 0)
+		fts := time.Unix(int64(startTimes[i]), 0).In(o.Location)
+		fto := time.Unix(int64(stopTimes[i]), 0).In(o.Location)
 
 		lf := LicelFile{
 			MeasurementSite:       sites[i],
@@ -520,7 +600,8 @@ func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
 			Laser3NShots:          int(l3ns[i]),
 			Laser3Freq:            int(l3f[i]),
 			NDatasets:             int(ndss[i]),
-			FileLoaded:            true,
+			FileLoaded:            !o.HeaderOnly,
+			HeaderOnly:            o.HeaderOnly,
 			Profiles:              make(LicelProfilesList, 0),
 		}
 		fileMap[fi] = &lf
@@ -544,23 +625,43 @@ func LoadLicelPackFromNetCDF3(fname string) (*LicelPack, error) {
 		}
 
 		pr := LicelProfile{
-			Active:       actives[j] != 0,
-			Photon:       isPhotons[j] != 0,
-			LaserType:    int(laserTypes[j]),
-			NDataPoints:  np,
-			Reserved:     [3]int{int(reserved0[j]), int(reserved1[j]), int(reserved2[j])},
-			HighVoltage:  int(highVoltages[j]),
-			BinWidth:     binWidths[j],
-			Wavelength:   wavelengths[j],
-			Polarization: polarizations[j],
-			BinShift:     int(binShifts[j]),
-			DecBinShift:  int(decBinShifts[j]),
-			AdcBits:      int(adcBits[j]),
-			NShots:       int(nshots[j]),
-			DiscrLevel:   discrLevels[j],
-			DeviceID:     deviceIDs[j],
-			NCrate:       int(nCrates[j]),
-			Data:         data,
+			Active:        actives[j] != 0,
+			Photon:        isPhotons[j] != 0,
+			LaserType:     int(laserTypes[j]),
+			NDataPoints:   np,
+			Reserved:      [3]int{int(reserved0[j]), int(reserved1[j]), int(reserved2[j])},
+			HighVoltage:   int(highVoltages[j]),
+			BinWidth:      binWidths[j],
+			Wavelength:    wavelengths[j],
+			Polarization:  polarizations[j],
+			BinShift:      int(binShifts[j]),
+			DecBinShift:   int(decBinShifts[j]),
+			AdcBits:       int(adcBits[j]),
+			NShots:        int(nshots[j]),
+			DiscrLevel:    discrLevels[j],
+			DeadTime:      deadTimes[j],
+			DeadTimeModel: DeadTimeModel(deadTimeModels[j]),
+			Background: Background{
+				BackgroundWindow: BackgroundWindow{Method: BackgroundMethod(bgMethods[j]), From: bgFrom[j], To: bgTo[j]},
+				Level:            bgLevels[j],
+				Slope:            bgSlopes[j],
+				Std:              bgStds[j],
+				Bins:             int(bgBins[j]),
+			},
+			DeviceID: deviceIDs[j],
+			NCrate:   int(nCrates[j]),
+		}
+		if !o.keepProfile(&pr) {
+			continue
+		}
+		if !o.HeaderOnly {
+			if legacyUnits && pr.Photon {
+				k := defaultBinTime / pr.binTime()
+				for i := range data {
+					data[i] *= k
+				}
+			}
+			pr.Data = data
 		}
 		lf.Profiles = append(lf.Profiles, pr)
 	}
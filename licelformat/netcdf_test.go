@@ -269,3 +269,32 @@ func TestLicelPack_SaveToNetCDF3_FileWithGluedProfile(t *testing.T) {
 	assert.True(t, glued.Profiles[0].IsGlued())
 	assert.Equal(t, []float64{1000, 2000}, glued.Profiles[0].Data)
 }
+
+func TestLoadLicelPackFromNetCDF3_WithOptions(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	pack, err := NewLicelPack(testFile)
+	require.NoError(t, err)
+
+	ncPath := filepath.Join(t.TempDir(), "opts.nc")
+	require.NoError(t, pack.SaveToNetCDF3(ncPath))
+
+	loaded, err := LoadLicelPackFromNetCDF3(ncPath)
+	require.NoError(t, err)
+	lf := loaded.Data[testFile]
+	assert.Equal(t, time.UTC, lf.MeasurementStartTime.Location())
+	assert.True(t, lf.MeasurementStartTime.Equal(pack.Data[testFile].MeasurementStartTime))
+
+	loc := time.FixedZone("UTC+10", 10*3600)
+	photon := func(pr *LicelProfile) bool { return pr.IsPhoton() }
+	loaded, err = LoadLicelPackFromNetCDF3(ncPath, WithLocation(loc), WithHeaderOnly(), WithProfileFilter(photon))
+	require.NoError(t, err)
+	lf = loaded.Data[testFile]
+	assert.Equal(t, loc, lf.MeasurementStartTime.Location())
+	assert.True(t, lf.HeaderOnly)
+	require.Len(t, lf.Profiles, 6)
+	assert.Equal(t, 6, lf.NDatasets)
+	for _, pr := range lf.Profiles {
+		assert.True(t, pr.IsPhoton())
+		assert.Nil(t, pr.Data)
+	}
+}
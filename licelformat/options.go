@@ -0,0 +1,181 @@
+package licelformat
+
+import "time"
+
+// LoadOptions — параметры загрузки LICEL-файлов, общие для всех загрузчиков
+// (LoadLicelFile, LoadLicelHeader, NewLicelPack, NewLicelPackFromZip, LicelReader,
+// LoadLicelPackFromNetCDF3). Нулевое значение полей соответствует поведению по умолчанию.
+type LoadOptions struct {
+	Location      *time.Location              // часовой пояс времён заголовка; nil — UTC
+	HeaderOnly    bool                        // не читать бинарные данные профилей
+	ProfileFilter func(pr *LicelProfile) bool // оставлять только профили, для которых true; nil — все
+	DiscardRaw    bool                        // не хранить исходные отсчёты в LicelProfile.Raw
+	Lenient       bool                        // восстанавливать повреждённые файлы, см. WithLenient
+	Limits        Limits                      // ограничения на значения из заголовка, см. WithLimits
+}
+
+// Ограничения по умолчанию на значения из заголовка (см. Limits).
+// Они заведомо больше, чем пишут транзиентные рекордеры Licel.
+const (
+	DefaultMaxDatasets   = 1024    // профилей в файле
+	DefaultMaxDataPoints = 1 << 20 // отсчётов в профиле (4 МиБ данных)
+	DefaultMaxFileSize   = 1 << 30 // байт в файле (1 ГиБ)
+)
+
+// Limits — верхние границы значений из заголовка, по которым загрузчик выделяет память.
+// Защищают от повреждённых и враждебных файлов, заявляющих гигабайты данных.
+// 0 — значение по умолчанию (DefaultMax*), отрицательное значение — без ограничения.
+// Превышение возвращается как *ParseError категории ErrLimitExceeded.
+type Limits struct {
+	MaxDatasets   int   // число профилей (NDatasets и фактические заголовки)
+	MaxDataPoints int   // NDataPoints одного профиля
+	MaxFileSize   int64 // размер файла, описанный заголовком; для zip — размер записи
+}
+
+// withDefaults — заменяет нулевые ограничения значениями по умолчанию
+func (l Limits) withDefaults() Limits {
+	if l.MaxDatasets == 0 {
+		l.MaxDatasets = DefaultMaxDatasets
+	}
+	if l.MaxDataPoints == 0 {
+		l.MaxDataPoints = DefaultMaxDataPoints
+	}
+	if l.MaxFileSize == 0 {
+		l.MaxFileSize = DefaultMaxFileSize
+	}
+	return l
+}
+
+// exceeds — превышает ли v ограничение max; отрицательный max — без ограничения
+func exceeds(v, max int64) bool {
+	return max >= 0 && v > max
+}
+
+// LoadOption — функциональная опция загрузки, изменяющая LoadOptions
+type LoadOption func(*LoadOptions)
+
+// newLoadOptions — параметры загрузки по умолчанию с применёнными опциями
+func newLoadOptions(opts []LoadOption) LoadOptions {
+	o := LoadOptions{Location: time.UTC}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Location == nil {
+		o.Location = time.UTC
+	}
+	o.Limits = o.Limits.withDefaults()
+	return o
+}
+
+// keepProfile — проходит ли профиль фильтр ProfileFilter
+func (o *LoadOptions) keepProfile(pr *LicelProfile) bool {
+	return o.ProfileFilter == nil || o.ProfileFilter(pr)
+}
+
+// WithLocation — часовой пояс, в котором записаны времена начала и окончания измерения.
+// По умолчанию UTC; nil также означает UTC.
+func WithLocation(loc *time.Location) LoadOption {
+	return func(o *LoadOptions) {
+		o.Location = loc
+	}
+}
+
+// WithHeaderOnly — загружать только заголовок: Data профилей остаётся пустым,
+// результат помечен HeaderOnly и не может быть сохранён
+func WithHeaderOnly() LoadOption {
+	return func(o *LoadOptions) {
+		o.HeaderOnly = true
+	}
+}
+
+// WithProfileFilter — загружать только профили, для которых cond возвращает true.
+// Данные остальных профилей пропускаются без декодирования, NDatasets уменьшается
+// до числа оставшихся профилей. Фильтр видит заголовок профиля без данных.
+func WithProfileFilter(cond func(pr *LicelProfile) bool) LoadOption {
+	return func(o *LoadOptions) {
+		o.ProfileFilter = cond
+	}
+}
+
+// WithoutRaw — не хранить исходные отсчёты в LicelProfile.Raw (экономия памяти).
+// Запись таких файлов восстанавливает отсчёты из Data делением на масштаб.
+func WithoutRaw() LoadOption {
+	return func(o *LoadOptions) {
+		o.DiscardRaw = true
+	}
+}
+
+// WithLenient — нестрогий разбор частично повреждённых файлов: вместо ошибки
+// возвращаются успешно прочитанные профили. Неполный профиль помечается Truncated,
+// профили без данных отбрасываются, NDatasets приводится к числу профилей;
+// каждая обойдённая проблема записывается в LicelFile.Warnings.
+// Ошибки в строках 1–3 и в заголовках профилей по-прежнему возвращаются.
+func WithLenient() LoadOption {
+	return func(o *LoadOptions) {
+		o.Lenient = true
+	}
+}
+
+// WithLimits — ограничения на число профилей, число отсчётов и размер файла.
+// Нулевые поля l заменяются значениями по умолчанию, отрицательные снимают ограничение.
+func WithLimits(l Limits) LoadOption {
+	return func(o *LoadOptions) {
+		o.Limits = l
+	}
+}
+
+// WriteOptions — параметры записи LICEL-файлов (WriteTo, Save, LicelPack.Save, SaveToZip)
+type WriteOptions struct {
+	Location       *time.Location      // часовой пояс, в котором записываются времена; nil — пояс MeasurementStartTime
+	Rounding       RoundingMode        // округление Data/scale до отсчёта; по умолчанию RoundNearest
+	OnClamp        func(r ClampReport) // приводить отсчёты к диапазону вместо ошибки и сообщать об этом; nil — ошибка
+	NegativeCounts bool                // разрешить отрицательные отсчёты (диапазон int32 вместо [0, MaxInt32])
+}
+
+// WriteOption — функциональная опция записи, изменяющая WriteOptions
+type WriteOption func(*WriteOptions)
+
+// newWriteOptions — параметры записи по умолчанию с применёнными опциями
+func newWriteOptions(opts []WriteOption) WriteOptions {
+	var o WriteOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithWriteLocation — часовой пояс, в котором времена записываются во вторую строку.
+// По умолчанию (и при nil) — пояс MeasurementStartTime, то есть пояс, заданный при загрузке.
+func WithWriteLocation(loc *time.Location) WriteOption {
+	return func(o *WriteOptions) {
+		o.Location = loc
+	}
+}
+
+// WithRounding — способ округления значений Data/scale до целых отсчётов
+// (по умолчанию RoundNearest). Нетронутые после загрузки отсчёты берутся из Raw и не округляются.
+func WithRounding(m RoundingMode) WriteOption {
+	return func(o *WriteOptions) {
+		o.Rounding = m
+	}
+}
+
+// WithClamp — записывать непредставимые значения границей диапазона (NaN — нулём)
+// вместо ошибки ErrNotRepresentable. onClamp вызывается для каждого профиля,
+// в котором были приведённые отсчёты; nil — отчёт не нужен.
+func WithClamp(onClamp func(r ClampReport)) WriteOption {
+	return func(o *WriteOptions) {
+		if onClamp == nil {
+			onClamp = func(ClampReport) {}
+		}
+		o.OnClamp = onClamp
+	}
+}
+
+// WithNegativeCounts — разрешить запись отрицательных отсчётов как знаковых int32
+// (например, после вычитания фона). По умолчанию отрицательный отсчёт — ошибка или, с WithClamp, 0.
+func WithNegativeCounts() WriteOption {
+	return func(o *WriteOptions) {
+		o.NegativeCounts = true
+	}
+}
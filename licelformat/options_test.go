@@ -0,0 +1,198 @@
+package licelformat
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// --- LoadOptions ---
+
+func TestNewLoadOptions_Defaults(t *testing.T) {
+	o := newLoadOptions(nil)
+	assert.Equal(t, time.UTC, o.Location)
+	assert.False(t, o.HeaderOnly)
+	assert.Nil(t, o.ProfileFilter)
+	assert.False(t, o.DiscardRaw)
+
+	o = newLoadOptions([]LoadOption{WithLocation(nil)})
+	assert.Equal(t, time.UTC, o.Location)
+}
+
+func TestNewLoadOptions_Custom(t *testing.T) {
+	// Пользовательская опция меняет LoadOptions напрямую
+	custom := func(o *LoadOptions) { o.DiscardRaw = true }
+	o := newLoadOptions([]LoadOption{WithHeaderOnly(), custom})
+	assert.True(t, o.HeaderOnly)
+	assert.True(t, o.DiscardRaw)
+}
+
+func TestLoadLicelFile_WithHeaderOnly(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	lf, err := LoadLicelFile(testFile, WithHeaderOnly())
+	require.NoError(t, err)
+	assert.True(t, lf.HeaderOnly)
+	assert.False(t, lf.FileLoaded)
+	require.Len(t, lf.Profiles, 12)
+	for _, pr := range lf.Profiles {
+		assert.Nil(t, pr.Data)
+	}
+
+	hdr, err := LoadLicelHeader(testFile)
+	require.NoError(t, err)
+	assert.Equal(t, hdr.Profiles, lf.Profiles)
+}
+
+func TestLoadLicelFile_WithProfileFilter(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	full, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	photon := func(pr *LicelProfile) bool { return pr.IsPhoton() }
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithProfileFilter(photon))
+	require.NoError(t, err)
+	require.Len(t, lf.Profiles, 6)
+	assert.Equal(t, 6, lf.NDatasets)
+	for i, pr := range lf.Profiles {
+		assert.True(t, pr.IsPhoton())
+		assert.Equal(t, full.Profiles[2*i+1].Data, pr.Data, "profile %d", i)
+		assert.Equal(t, full.Profiles[2*i+1].DataOffset, pr.DataOffset, "profile %d", i)
+	}
+
+	// Сохранённый файл содержит только отобранные профили
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	back, err := LoadLicelFileFromReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 6, back.NDatasets)
+	assert.Equal(t, lf.Profiles[0].Data, back.Profiles[0].Data)
+
+	hdr, err := LoadLicelHeaderFromReader(bytes.NewReader(raw), WithProfileFilter(photon))
+	require.NoError(t, err)
+	assert.Len(t, hdr.Profiles, 6)
+}
+
+func TestLoadLicelFile_WithoutRaw(t *testing.T) {
+	raw, err := os.ReadFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	full, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithoutRaw())
+	require.NoError(t, err)
+	for i, pr := range lf.Profiles {
+		assert.Nil(t, pr.Raw, "profile %d", i)
+		assert.Equal(t, full.Profiles[i].Data, pr.Data, "profile %d", i)
+	}
+}
+
+func TestNewLicelPack_WithOptions(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	analog532 := func(pr *LicelProfile) bool { return pr.IsAnalog() && pr.Wavelength == 532 }
+	pack, err := NewLicelPack(testFile, WithProfileFilter(analog532), WithoutRaw())
+	require.NoError(t, err)
+	lf := pack.Data[testFile]
+	require.Len(t, lf.Profiles, 2)
+	for _, pr := range lf.Profiles {
+		assert.True(t, pr.IsAnalog())
+		assert.Nil(t, pr.Raw)
+		assert.NotEmpty(t, pr.Data)
+	}
+}
+
+func TestLicelReader_WithOptions(t *testing.T) {
+	testFile := filepath.Join("..", "testdata", "b2021019.223500")
+	full, err := LoadLicelFile(testFile)
+	require.NoError(t, err)
+
+	lr, err := OpenLicelReader(testFile, WithProfileFilter(func(pr *LicelProfile) bool { return pr.Wavelength == 1064 }), WithoutRaw())
+	require.NoError(t, err)
+	defer lr.Close()
+
+	require.Equal(t, 1, lr.NProfiles())
+	pr, err := lr.ReadProfile(0)
+	require.NoError(t, err)
+	assert.Nil(t, pr.Raw)
+	assert.Equal(t, full.Profiles[10].Data, pr.Data)
+}
+
+// --- Limits ---
+
+func TestNewLoadOptions_LimitsDefaults(t *testing.T) {
+	o := newLoadOptions(nil)
+	assert.Equal(t, Limits{DefaultMaxDatasets, DefaultMaxDataPoints, DefaultMaxFileSize}, o.Limits)
+
+	o = newLoadOptions([]LoadOption{WithLimits(Limits{MaxDatasets: 4, MaxFileSize: -1})})
+	assert.Equal(t, Limits{4, DefaultMaxDataPoints, -1}, o.Limits)
+}
+
+func TestLoadLicelFile_Limits(t *testing.T) {
+	raw := lenientTestBytes("02")
+
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLimits(Limits{MaxDatasets: 1}))
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, 3, pe.Line)
+	assert.Equal(t, "dataset count", pe.Field)
+	assert.Equal(t, "2", pe.Token)
+
+	_, err = LoadLicelFileFromReader(bytes.NewReader(raw), WithLimits(Limits{MaxDataPoints: 2}))
+	require.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, 4, pe.Line)
+	assert.Equal(t, 0, pe.Profile)
+	assert.Equal(t, "data points", pe.Field)
+
+	_, err = LoadLicelFileFromReader(bytes.NewReader(raw), WithLimits(Limits{MaxFileSize: int64(len(raw) - 1)}))
+	require.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, "file size", pe.Field)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLimits(Limits{MaxDatasets: 2, MaxDataPoints: 3, MaxFileSize: int64(len(raw))}))
+	require.NoError(t, err)
+	assert.Len(t, lf.Profiles, 2)
+}
+
+func TestLoadLicelFile_HugeHeaderValues(t *testing.T) {
+	// Заявленные размеры не должны приводить к выделению памяти до проверки
+	raw := bytes.Replace(lenientTestBytes("02"), []byte(" 1 1 1 00003 1 0850 3.75 00532.p"), []byte(" 1 1 1 999999999999 1 0850 3.75 00532.p"), 1)
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	raw = bytes.Replace(lenientTestBytes("02"), []byte(" 0000000 0000 02 "), []byte(" 0000000 0000 2000000000 "), 1)
+	_, err = LoadLicelFileFromReader(bytes.NewReader(raw))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+}
+
+func TestLoadLicelFile_LongHeaderLine(t *testing.T) {
+	raw := append([]byte(" "), bytes.Repeat([]byte("x"), 2*maxHeaderLineLen)...)
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	var pe *ParseError
+	require.ErrorAs(t, err, &pe)
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.Equal(t, 1, pe.Line)
+}
+
+func TestNewLicelPackFromZip_MaxFileSize(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "pack.zip")
+	pack := &LicelPack{Data: map[string]LicelFile{}}
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(lenientTestBytes("02")))
+	require.NoError(t, err)
+	pack.Data["b0000001.000000"] = lf
+	require.NoError(t, pack.SaveToZip(zipPath))
+
+	_, err = NewLicelPackFromZip(zipPath, WithLimits(Limits{MaxFileSize: 10}))
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+
+	loaded, err := NewLicelPackFromZip(zipPath)
+	require.NoError(t, err)
+	assert.Len(t, loaded.Data, 1)
+}
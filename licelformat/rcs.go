@@ -0,0 +1,78 @@
+package licelformat
+
+import "fmt"
+
+// RangeCorrected — новый профиль с сигналом, исправленным на квадрат дальности: P(r)·r²,
+// где r — Ranges(). Если bg.Method не BackgroundNone, из копии данных сначала вычитается
+// фон (см. SubtractBackground); у профиля с уже вычтенным фоном это ошибка.
+// Результат помечен DeviceIDRangeCorrected (Units — мВ·м² или МГц·м²), не содержит Raw
+// и предназначен для NetCDF и графиков; исходный профиль не изменяется.
+func (lp *LicelProfile) RangeCorrected(bg BackgroundWindow) (LicelProfile, error) {
+	if lp.IsRangeCorrected() {
+		return LicelProfile{}, fmt.Errorf("RangeCorrected: profile is already range-corrected")
+	}
+	if lp.BinWidth <= 0 {
+		return LicelProfile{}, fmt.Errorf("RangeCorrected: bin width must be positive, got %.2f", lp.BinWidth)
+	}
+
+	rc := *lp
+	rc.Data = append([]float64(nil), lp.Data...)
+	rc.Raw = nil
+	rc.rawLine, rc.rawKey = "", ""
+	rc.DataOffset = 0
+	if bg.Method != BackgroundNone {
+		if err := rc.SubtractBackground(bg); err != nil {
+			return LicelProfile{}, fmt.Errorf("RangeCorrected: %w", err)
+		}
+	}
+
+	zero := rc.ZeroBin()
+	for i := range rc.Data {
+		r := (float64(i) - zero) * rc.BinWidth
+		rc.Data[i] *= r * r
+	}
+	rc.NDataPoints = len(rc.Data)
+	rc.DeviceID = DeviceIDRangeCorrected
+	return rc, nil
+}
+
+// RangeCorrected — копия файла, в которой вместо профилей — их RangeCorrected-версии.
+// Профили без данных и уже исправленные на квадрат дальности пропускаются.
+func (lf *LicelFile) RangeCorrected(bg BackgroundWindow) (LicelFile, error) {
+	res := *lf
+	res.Profiles = make(LicelProfilesList, 0, len(lf.Profiles))
+	for i := range lf.Profiles {
+		pr := &lf.Profiles[i]
+		if len(pr.Data) == 0 || pr.IsRangeCorrected() {
+			continue
+		}
+		rc, err := pr.RangeCorrected(bg)
+		if err != nil {
+			return LicelFile{}, fmt.Errorf("profile %d: %w", i, err)
+		}
+		res.Profiles = append(res.Profiles, rc)
+	}
+	res.NDatasets = len(res.Profiles)
+	return res, nil
+}
+
+// RangeCorrected — новый пак с RangeCorrected-профилями всех файлов (см. LicelFile.RangeCorrected),
+// отдельный от исходных каналов. Файлы без подходящих профилей не включаются; исходный пак не изменяется.
+// Чтобы выбрать каналы, примените к паку FilterProfiles.
+func (lp *LicelPack) RangeCorrected(bg BackgroundWindow) (LicelPack, error) {
+	result := LicelPack{
+		Data:                make(map[string]LicelFile, len(lp.Data)),
+		ZipCompressionLevel: lp.ZipCompressionLevel,
+	}
+	for fname, lf := range lp.Data {
+		rc, err := lf.RangeCorrected(bg)
+		if err != nil {
+			return LicelPack{}, fmt.Errorf("%s: %w", fname, err)
+		}
+		if len(rc.Profiles) > 0 {
+			result.Data[fname] = rc
+		}
+	}
+	result.StartTime, result.StopTime = timeBounds(result.Data)
+	return result, nil
+}
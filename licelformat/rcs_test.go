@@ -0,0 +1,79 @@
+package licelformat
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicelProfile_RangeCorrected(t *testing.T) {
+	pr := LicelProfile{Photon: true, DeviceID: DeviceIDPhoton, NShots: 20, BinWidth: 10, BinShift: 1, NDataPoints: 4}
+	pr.decodeData([]byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0})
+	orig := append([]float64(nil), pr.Data...)
+
+	rc, err := pr.RangeCorrected(BackgroundWindow{})
+	require.NoError(t, err)
+	// дальности −10, 0, 10, 20 м
+	assert.InDeltaSlice(t, []float64{orig[0] * 100, 0, orig[2] * 100, orig[3] * 400}, rc.Data, 1e-9)
+	assert.Equal(t, DeviceIDRangeCorrected, rc.DeviceID)
+	assert.True(t, rc.IsRangeCorrected())
+	assert.Equal(t, UnitMHzM2, rc.Units())
+	assert.Nil(t, rc.Raw)
+	assert.Equal(t, orig, pr.Data)
+	assert.Equal(t, DeviceIDPhoton, pr.DeviceID)
+
+	_, err = rc.RangeCorrected(BackgroundWindow{})
+	assert.Error(t, err)
+	_, err = rc.Convert(UnitCounts)
+	assert.Error(t, err)
+}
+
+func TestLicelProfile_RangeCorrected_Background(t *testing.T) {
+	pr := LicelProfile{DeviceID: DeviceIDAnalog, BinWidth: 10, NDataPoints: 4, Data: []float64{9, 7, 5, 5}}
+	rc, err := pr.RangeCorrected(BackgroundWindow{Method: BackgroundMean, From: 20})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0, 200, 0, 0}, rc.Data, 1e-9)
+	assert.Equal(t, UnitMillivoltsM2, rc.Units())
+	assert.InDelta(t, 5, rc.Background.Level, 1e-12)
+	assert.Error(t, rc.RestoreBackground())
+	assert.Equal(t, BackgroundNone, pr.Background.Method)
+
+	require.NoError(t, pr.SubtractBackground(BackgroundWindow{Method: BackgroundMean, From: 20}))
+	_, err = pr.RangeCorrected(BackgroundWindow{Method: BackgroundMean, From: 20})
+	assert.Error(t, err)
+}
+
+func TestLicelPack_RangeCorrected(t *testing.T) {
+	pack := testPack(
+		LicelProfile{DeviceID: DeviceIDAnalog, Polarization: "o", NShots: 10, BinWidth: 10, NDataPoints: 3, Data: []float64{1, 1, 1}},
+		LicelProfile{DeviceID: DeviceIDPhoton, Polarization: "o", NShots: 10, Photon: true, BinWidth: 10, NDataPoints: 3, Data: []float64{2, 2, 2}},
+	)
+
+	rcs, err := pack.RangeCorrected(BackgroundWindow{})
+	require.NoError(t, err)
+	require.Len(t, rcs.Data, 2)
+	eachFile(t, rcs, func(t *testing.T, lf LicelFile) {
+		require.Len(t, lf.Profiles, 2)
+		assert.Equal(t, 2, lf.NDatasets)
+		assert.Equal(t, []float64{0, 100, 400}, lf.Profiles[0].Data)
+		assert.Equal(t, []float64{0, 200, 800}, lf.Profiles[1].Data)
+	})
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, DeviceIDAnalog, lf.Profiles[0].DeviceID)
+		assert.Equal(t, []float64{1, 1, 1}, lf.Profiles[0].Data)
+	})
+
+	fname := filepath.Join(t.TempDir(), "rcs.nc")
+	require.NoError(t, rcs.SaveToNetCDF3(fname))
+	loaded, err := LoadLicelPackFromNetCDF3(fname)
+	require.NoError(t, err)
+	got := loaded.Data["/data/a"].Profiles
+	assert.True(t, got[0].IsRangeCorrected())
+	assert.Equal(t, UnitMHzM2, got[1].Units())
+	assert.Equal(t, []float64{0, 200, 800}, got[1].Data)
+
+	_, err = pack.RangeCorrected(BackgroundWindow{Method: BackgroundMean, From: 100})
+	assert.Error(t, err)
+}
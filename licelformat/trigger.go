@@ -0,0 +1,113 @@
+package licelformat
+
+import (
+	"fmt"
+	"math"
+)
+
+// TriggerDelay — положение нулевой дальности для каналов, выбранных Channel, в бинах.
+// Задаёт измеренную задержку запуска рекордера вместо BinShift/DecBinShift из заголовка.
+type TriggerDelay struct {
+	Channel
+	ZeroBin float64 `json:"zero_bin"`
+}
+
+// AlignZeroBin — сдвигает Data так, чтобы нулевая дальность, находящаяся в бине zeroBin,
+// пришлась на бин 0. Первые бины отбрасываются, дробный сдвиг выполняется линейной
+// интерполяцией соседних бинов; NDataPoints уменьшается. При целом сдвиге Raw сдвигается
+// вместе с Data, при дробном — сбрасывается. BinShift и DecBinShift обнуляются,
+// поэтому повторное выравнивание по заголовку ничего не меняет. Нулевой сдвиг Data не меняет,
+// в том числе у профиля без данных.
+func (lp *LicelProfile) AlignZeroBin(zeroBin float64) error {
+	apply, err := lp.zeroBinAlignment(zeroBin)
+	if err != nil {
+		return err
+	}
+	apply()
+	return nil
+}
+
+// zeroBinAlignment — проверяет сдвиг AlignZeroBin и возвращает функцию, которая его выполняет
+func (lp *LicelProfile) zeroBinAlignment(zeroBin float64) (func(), error) {
+	if zeroBin < 0 || math.IsNaN(zeroBin) || math.IsInf(zeroBin, 0) {
+		return nil, fmt.Errorf("AlignZeroBin: zero bin must be non-negative, got %g", zeroBin)
+	}
+	if zeroBin == 0 {
+		return func() { lp.BinShift, lp.DecBinShift = 0, 0 }, nil
+	}
+	n := len(lp.Data)
+	s := int(zeroBin)
+	f := zeroBin - float64(s)
+	m := n - s
+	if f > 0 {
+		m--
+	}
+	if m <= 0 {
+		return nil, fmt.Errorf("AlignZeroBin: zero bin %g leaves no data of %d points", zeroBin, n)
+	}
+
+	return func() {
+		if f == 0 {
+			copy(lp.Data, lp.Data[s:])
+		} else {
+			for i := 0; i < m; i++ {
+				lp.Data[i] = (1-f)*lp.Data[i+s] + f*lp.Data[i+s+1]
+			}
+		}
+		lp.Data = lp.Data[:m]
+		if f == 0 && len(lp.Raw) == n {
+			copy(lp.Raw, lp.Raw[s:])
+			lp.Raw = lp.Raw[:m]
+		} else {
+			lp.Raw = nil
+		}
+		lp.NDataPoints = m
+		lp.BinShift, lp.DecBinShift = 0, 0
+	}, nil
+}
+
+// AlignZeroBins — выравнивает нулевую дальность всех профилей файла (см. AlignZeroBin).
+// Для профиля берётся первая подходящая задержка из delays, иначе ZeroBin() его заголовка.
+// Профили без данных (WithHeaderOnly, отброшенные нестрогим разбором) пропускаются.
+// Сдвиги сначала проверяются для всех профилей: при ошибке файл не изменяется.
+func (lf *LicelFile) AlignZeroBins(delays ...TriggerDelay) error {
+	updates, err := lf.zeroBinAlignments(delays)
+	if err != nil {
+		return err
+	}
+	applyUpdates(updates)
+	return nil
+}
+
+// zeroBinAlignments — проверенные сдвиги нулевой дальности профилей файла
+func (lf *LicelFile) zeroBinAlignments(delays []TriggerDelay) ([]func(), error) {
+	updates := make([]func(), 0, len(lf.Profiles))
+	for i := range lf.Profiles {
+		pr := &lf.Profiles[i]
+		if len(pr.Data) == 0 {
+			continue
+		}
+		zeroBin := pr.ZeroBin()
+		for _, d := range delays {
+			if d.Match(pr) {
+				zeroBin = d.ZeroBin
+				break
+			}
+		}
+		apply, err := pr.zeroBinAlignment(zeroBin)
+		if err != nil {
+			return nil, fmt.Errorf("profile %d: %w", i, err)
+		}
+		updates = append(updates, apply)
+	}
+	return updates, nil
+}
+
+// AlignZeroBins — выравнивает нулевую дальность профилей во всех файлах пака
+// с одними и теми же задержками delays (см. LicelFile.AlignZeroBins).
+// При ошибке в любом файле пак не изменяется.
+func (lp *LicelPack) AlignZeroBins(delays ...TriggerDelay) error {
+	return lp.updateFiles(func(_ string, lf *LicelFile) ([]func(), error) {
+		return lf.zeroBinAlignments(delays)
+	})
+}
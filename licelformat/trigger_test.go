@@ -0,0 +1,100 @@
+package licelformat
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannel_Match(t *testing.T) {
+	pr := LicelProfile{Wavelength: 355, Polarization: "o", DeviceID: DeviceIDPhoton}
+	assert.True(t, Channel{}.Match(&pr))
+	assert.True(t, Channel{Wavelength: 355, DeviceID: DeviceIDPhoton}.Match(&pr))
+	assert.False(t, Channel{Wavelength: 532}.Match(&pr))
+	assert.False(t, Channel{Polarization: "p"}.Match(&pr))
+	assert.False(t, Channel{DeviceID: DeviceIDAnalog}.Match(&pr))
+
+	assert.Equal(t, "355.o BC", Channel{Wavelength: 355, Polarization: "o", DeviceID: "BC"}.String())
+	assert.Equal(t, "*.* BT", Channel{DeviceID: "BT"}.String())
+}
+
+func TestLicelProfile_AlignZeroBin_Integer(t *testing.T) {
+	pr := LicelProfile{Photon: true, NShots: 20, BinShift: 2, NDataPoints: 5}
+	pr.decodeData([]byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0, 4, 0, 0, 0, 5, 0, 0, 0})
+	scale := pr.scaleFactor()
+
+	require.NoError(t, pr.AlignZeroBin(pr.ZeroBin()))
+	assert.Equal(t, []int32{3, 4, 5}, pr.Raw)
+	assert.InDeltaSlice(t, []float64{3 * scale, 4 * scale, 5 * scale}, pr.Data, 1e-12)
+	assert.Equal(t, 3, pr.NDataPoints)
+	assert.Equal(t, 0.0, pr.ZeroBin())
+
+	// повторное выравнивание по заголовку ничего не меняет
+	require.NoError(t, pr.AlignZeroBin(pr.ZeroBin()))
+	assert.Len(t, pr.Data, 3)
+}
+
+func TestLicelProfile_AlignZeroBin_Fractional(t *testing.T) {
+	pr := LicelProfile{NDataPoints: 4, Data: []float64{0, 10, 20, 30}, Raw: []int32{0, 1, 2, 3}}
+	require.NoError(t, pr.AlignZeroBin(1.25))
+	assert.InDeltaSlice(t, []float64{12.5, 22.5}, pr.Data, 1e-12)
+	assert.Nil(t, pr.Raw)
+	assert.Equal(t, 2, pr.NDataPoints)
+}
+
+func TestLicelProfile_AlignZeroBin_Errors(t *testing.T) {
+	pr := LicelProfile{NDataPoints: 3, Data: []float64{1, 2, 3}}
+	assert.Error(t, pr.AlignZeroBin(-1))
+	assert.Error(t, pr.AlignZeroBin(3))
+	assert.Error(t, pr.AlignZeroBin(2.5))
+	assert.Equal(t, []float64{1, 2, 3}, pr.Data)
+}
+
+func TestLicelPack_AlignZeroBins(t *testing.T) {
+	pack := testPack(
+		LicelProfile{Wavelength: 355, DeviceID: DeviceIDAnalog, BinShift: 1, NDataPoints: 4, Data: []float64{1, 2, 3, 4}},
+		LicelProfile{Wavelength: 355, DeviceID: DeviceIDPhoton, NDataPoints: 4, Data: []float64{1, 2, 3, 4}},
+	)
+
+	// аналоговый канал — по заголовку, фотонный — заданная задержка
+	err := pack.AlignZeroBins(TriggerDelay{Channel: Channel{DeviceID: DeviceIDPhoton}, ZeroBin: 2})
+	require.NoError(t, err)
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, []float64{2, 3, 4}, lf.Profiles[0].Data)
+		assert.Equal(t, []float64{3, 4}, lf.Profiles[1].Data)
+	})
+
+	err = pack.AlignZeroBins(TriggerDelay{ZeroBin: 10})
+	assert.Error(t, err)
+}
+
+func TestLicelPack_AlignZeroBins_Atomic(t *testing.T) {
+	pr := LicelProfile{BinShift: 1, NDataPoints: 4, Data: []float64{1, 2, 3, 4}}
+	pack := testPack(pr, pr)
+	pack.Data["/data/b"].Profiles[1].BinShift = 5 // сдвиг на 5 бинов не оставляет данных
+
+	err := pack.AlignZeroBins()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "/data/b: profile 1")
+	eachFile(t, pack, func(t *testing.T, lf LicelFile) {
+		assert.Equal(t, []float64{1, 2, 3, 4}, lf.Profiles[0].Data)
+		assert.Equal(t, 1, lf.Profiles[0].BinShift)
+	})
+}
+
+func TestLicelFile_AlignZeroBins_NoData(t *testing.T) {
+	empty := LicelProfile{BinShift: 2, NDataPoints: 4}
+	require.NoError(t, empty.AlignZeroBin(0))
+	assert.Zero(t, empty.BinShift)
+	assert.Error(t, empty.AlignZeroBin(1))
+
+	lf := LicelFile{NDatasets: 2, Profiles: LicelProfilesList{
+		{BinShift: 2, NDataPoints: 4},
+		{BinShift: 1, NDataPoints: 3, Data: []float64{1, 2, 3}},
+	}}
+	require.NoError(t, lf.AlignZeroBins())
+	assert.Nil(t, lf.Profiles[0].Data)
+	assert.Equal(t, 2, lf.Profiles[0].BinShift)
+	assert.Equal(t, []float64{2, 3}, lf.Profiles[1].Data)
+}
@@ -0,0 +1,107 @@
+package licelformat
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Unit — единица измерения значений профиля
+type Unit string
+
+const (
+	UnitCounts         Unit = "counts"       // сумма отсчётов АЦП/счётчика за все импульсы (как в Raw)
+	UnitMillivolts     Unit = "mV"           // средний за импульс аналоговый сигнал
+	UnitMHz            Unit = "MHz"          // скорость счёта фотонов
+	UnitPhotonsPerShot Unit = "photons/shot" // среднее число фотонов в бине за импульс
+
+	UnitMillivoltsM2 Unit = "mV m2"  // аналоговый сигнал, исправленный на квадрат дальности
+	UnitMHzM2        Unit = "MHz m2" // скорость счёта, исправленная на квадрат дальности
+)
+
+// Licel связывает ширину бина со временем бина через c = 3·10⁸ м/с: 7.5 м ↔ 50 нс (20 МГц)
+const (
+	licelHalfLightSpeed   = 150.0 // c/2, м/мкс
+	defaultPhotonBinWidth = 7.5   // ширина бина, если в заголовке она не положительна, м
+	defaultBinTime        = defaultPhotonBinWidth / licelHalfLightSpeed
+)
+
+// Units — единица измерения Data: мВ для аналоговых (и склеенных) каналов, МГц для фотонных;
+// для профилей RangeCorrected — те же единицы, умноженные на м²
+func (lp *LicelProfile) Units() Unit {
+	switch {
+	case lp.IsRangeCorrected() && lp.Photon:
+		return UnitMHzM2
+	case lp.IsRangeCorrected():
+		return UnitMillivoltsM2
+	case lp.Photon:
+		return UnitMHz
+	}
+	return UnitMillivolts
+}
+
+// binTime — длительность бина, мкс. При BinWidth ≤ 0 берётся 50 нс (20 МГц),
+// которые раньше подразумевались для всех фотонных каналов.
+func (lp *LicelProfile) binTime() float64 {
+	if lp.BinWidth <= 0 {
+		return defaultBinTime
+	}
+	return lp.BinWidth / licelHalfLightSpeed
+}
+
+// unitFactor — значение одного отсчёта в единицах u
+func (lp *LicelProfile) unitFactor(u Unit) (float64, error) {
+	if u != UnitCounts && lp.NShots <= 0 {
+		return 0, fmt.Errorf("converting to %s: n shots must be positive, got %d", u, lp.NShots)
+	}
+	switch u {
+	case UnitCounts:
+		return 1, nil
+	case UnitMillivolts:
+		if lp.Photon {
+			return 0, fmt.Errorf("photon counting channel cannot be converted to %s", u)
+		}
+		return lp.scaleFactor(), nil
+	case UnitMHz:
+		if !lp.Photon {
+			return 0, fmt.Errorf("analog channel cannot be converted to %s", u)
+		}
+		return lp.scaleFactor(), nil
+	case UnitPhotonsPerShot:
+		if !lp.Photon {
+			return 0, fmt.Errorf("analog channel cannot be converted to %s", u)
+		}
+		return 1.0 / float64(lp.NShots), nil
+	}
+	return 0, fmt.Errorf("unknown unit %q", u)
+}
+
+// Convert — значения Data в единицах to (UnitCounts, UnitMillivolts для аналоговых каналов,
+// UnitMHz и UnitPhotonsPerShot для фотонных). Возвращается новый срез, профиль не изменяется.
+func (lp *LicelProfile) Convert(to Unit) ([]float64, error) {
+	if lp.IsRangeCorrected() {
+		return nil, fmt.Errorf("range-corrected profile cannot be converted to %s", to)
+	}
+	from, err := lp.unitFactor(lp.Units())
+	if err != nil {
+		return nil, err
+	}
+	k, err := lp.unitFactor(to)
+	if err != nil {
+		return nil, err
+	}
+	k /= from
+	out := make([]float64, len(lp.Data))
+	for i, v := range lp.Data {
+		out[i] = v * k
+	}
+	return out, nil
+}
+
+// MarshalJSON — поля профиля с json-тегами и единица измерения Data ("units")
+func (lp LicelProfile) MarshalJSON() ([]byte, error) {
+	type profile LicelProfile // без методов, чтобы не вызвать MarshalJSON рекурсивно
+	return json.Marshal(struct {
+		profile
+		Units Unit `json:"units"`
+	}{profile(lp), lp.Units()})
+}
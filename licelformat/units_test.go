@@ -0,0 +1,111 @@
+package licelformat
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/batchatco/go-native-netcdf/netcdf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLicelProfile_Units(t *testing.T) {
+	assert.Equal(t, UnitMHz, (&LicelProfile{Photon: true}).Units())
+	assert.Equal(t, UnitMillivolts, (&LicelProfile{DeviceID: DeviceIDAnalog}).Units())
+	assert.Equal(t, UnitMillivolts, (&LicelProfile{DeviceID: DeviceIDGlued}).Units())
+}
+
+func TestScaleFactor_PhotonUsesBinWidth(t *testing.T) {
+	// 3.75 м ↔ 25 нс (40 МГц): 1 отсчёт за 1000 импульсов = 1/(1000·0.025 мкс) = 0.04 МГц
+	pr := LicelProfile{Photon: true, NShots: 1000, BinWidth: 3.75}
+	assert.InDelta(t, 0.04, pr.scaleFactor(), 1e-12)
+
+	pr.BinWidth = 7.5
+	assert.InDelta(t, 1.0/(1000*0.05), pr.scaleFactor(), 1e-12)
+}
+
+func TestLicelProfile_Convert_Photon(t *testing.T) {
+	pr := LicelProfile{Photon: true, NShots: 1000, BinWidth: 3.75}
+	pr.decodeData([]byte{0, 0, 0, 0, 10, 0, 0, 0, 250, 0, 0, 0})
+
+	counts, err := pr.Convert(UnitCounts)
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0, 10, 250}, counts, 1e-9)
+
+	perShot, err := pr.Convert(UnitPhotonsPerShot)
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0, 0.01, 0.25}, perShot, 1e-12)
+
+	mhz, err := pr.Convert(UnitMHz)
+	require.NoError(t, err)
+	assert.Equal(t, pr.Data, mhz)
+
+	_, err = pr.Convert(UnitMillivolts)
+	assert.Error(t, err)
+}
+
+func TestLicelProfile_Convert_Analog(t *testing.T) {
+	pr := LicelProfile{NShots: 100, AdcBits: 12, DiscrLevel: 500}
+	pr.decodeData([]byte{0, 0x10, 0, 0, 0, 0x20, 0, 0})
+	assert.InDeltaSlice(t, []float64{5000, 10000}, pr.Data, 1e-9)
+
+	counts, err := pr.Convert(UnitCounts)
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{4096, 8192}, counts, 1e-9)
+
+	for _, u := range []Unit{UnitMHz, UnitPhotonsPerShot, Unit("W")} {
+		_, err := pr.Convert(u)
+		assert.Error(t, err, u)
+	}
+
+	pr.NShots = 0
+	_, err = pr.Convert(UnitCounts)
+	assert.Error(t, err)
+}
+
+func TestLicelProfile_MarshalJSON_Units(t *testing.T) {
+	b, err := json.Marshal(LicelFile{Profiles: LicelProfilesList{
+		{Photon: true, Wavelength: 355},
+		{Wavelength: 532},
+	}})
+	require.NoError(t, err)
+
+	var got struct {
+		Profiles []map[string]any `json:"datasets"`
+	}
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Len(t, got.Profiles, 2)
+	assert.Equal(t, "MHz", got.Profiles[0]["units"])
+	assert.Equal(t, "mV", got.Profiles[1]["units"])
+	assert.Equal(t, 532.0, got.Profiles[1]["wavelength"])
+}
+
+func TestLicelPack_SaveToNetCDF3_Units(t *testing.T) {
+	pack := &LicelPack{Data: map[string]LicelFile{
+		"/data/a": {MeasurementSite: "Site", NDatasets: 2, Profiles: LicelProfilesList{
+			{Photon: true, NShots: 10, BinWidth: 7.5, Polarization: "o", DeviceID: DeviceIDPhoton, NDataPoints: 1, Data: []float64{1}},
+			{NShots: 10, AdcBits: 12, DiscrLevel: 500, BinWidth: 7.5, Polarization: "o", DeviceID: DeviceIDAnalog, NDataPoints: 1, Data: []float64{2}},
+		}},
+	}}
+	fname := filepath.Join(t.TempDir(), "units.nc")
+	require.NoError(t, pack.SaveToNetCDF3(fname))
+
+	nc, err := netcdf.Open(fname)
+	require.NoError(t, err)
+	defer nc.Close()
+	units := readStrings(nc, "units", 2)
+	for i := range units {
+		units[i] = strings.TrimRight(units[i], "\x00") // строки дополняются NUL до общей длины
+	}
+	assert.Equal(t, []string{"MHz", "mV"}, units)
+	signal, err := nc.GetVariable("signal")
+	require.NoError(t, err)
+	u, _ := signal.Attributes.Get("units")
+	assert.Equal(t, "mixed", u)
+
+	loaded, err := LoadLicelPackFromNetCDF3(fname)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{1}, loaded.Data["/data/a"].Profiles[0].Data)
+}
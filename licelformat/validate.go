@@ -0,0 +1,221 @@
+package licelformat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity — серьёзность проблемы, найденной Validate
+type Severity int
+
+const (
+	SeverityInfo    Severity = iota // замечание, данные пригодны
+	SeverityWarning                 // подозрительное значение, обработка возможна
+	SeverityError                   // нарушен инвариант, на который опирается обработка
+)
+
+// String — название уровня серьёзности
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// MarshalText — уровень серьёзности в JSON выводится названием
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// ValidationIssue — одна проблема, найденная Validate
+type ValidationIssue struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file,omitempty"`  // имя файла в паке; "" — файл или проблема всего пака
+	Profile  int      `json:"profile"`         // индекс профиля; -1 — проблема файла или пака
+	Field    string   `json:"field,omitempty"` // проверяемое поле
+	Message  string   `json:"message"`
+}
+
+// String — описание проблемы в одну строку
+func (vi ValidationIssue) String() string {
+	var b strings.Builder
+	b.WriteString(vi.Severity.String())
+	if vi.File != "" {
+		fmt.Fprintf(&b, ": %s", vi.File)
+	}
+	if vi.Profile >= 0 {
+		fmt.Fprintf(&b, ": profile %d", vi.Profile)
+	}
+	if vi.Field != "" {
+		fmt.Fprintf(&b, ": %s", vi.Field)
+	}
+	fmt.Fprintf(&b, ": %s", vi.Message)
+	return b.String()
+}
+
+// ValidationReport — результат проверки файла или пака
+type ValidationReport struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+// HasErrors — есть ли проблемы уровня SeverityError
+func (r *ValidationReport) HasErrors() bool {
+	return r.Count(SeverityError) > 0
+}
+
+// Count — число проблем уровня не ниже min
+func (r *ValidationReport) Count(min Severity) int {
+	n := 0
+	for _, vi := range r.Issues {
+		if vi.Severity >= min {
+			n++
+		}
+	}
+	return n
+}
+
+// add — добавляет проблему в отчёт
+func (r *ValidationReport) add(sev Severity, profile int, field, format string, args ...any) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Severity: sev,
+		Profile:  profile,
+		Field:    field,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// knownDeviceIDs — идентификаторы устройств, которые понимает библиотека
+var knownDeviceIDs = map[string]bool{
+	DeviceIDAnalog:     true,
+	DeviceIDPhoton:     true,
+	DeviceIDGlued:      true,
+	DeviceIDPhotodiode: true,
+	DeviceIDPowerMeter: true,
+
+	DeviceIDRangeCorrected: true,
+}
+
+// Validate — проверяет инварианты, на которые опираются обработка и запись файла.
+// Проверка не останавливается на первой проблеме: все найденные проблемы
+// возвращаются в отчёте. Для файлов HeaderOnly данные профилей не проверяются.
+func (lf *LicelFile) Validate() ValidationReport {
+	var r ValidationReport
+
+	if lf.NDatasets != len(lf.Profiles) {
+		r.add(SeverityError, -1, "dataset count", "NDatasets is %d, but there are %d profiles", lf.NDatasets, len(lf.Profiles))
+	}
+	switch {
+	case lf.MeasurementStopTime.Before(lf.MeasurementStartTime):
+		r.add(SeverityError, -1, "stop time", "stop time %s is before start time %s", lf.MeasurementStopTime, lf.MeasurementStartTime)
+	case lf.MeasurementStopTime.Equal(lf.MeasurementStartTime):
+		r.add(SeverityWarning, -1, "stop time", "stop time equals start time %s", lf.MeasurementStartTime)
+	}
+	if lf.Latitude < -90 || lf.Latitude > 90 {
+		r.add(SeverityError, -1, "latitude", "%g is outside [-90, 90]", lf.Latitude)
+	}
+	if lf.Longitude < -180 || lf.Longitude > 180 {
+		r.add(SeverityError, -1, "longitude", "%g is outside [-180, 180]", lf.Longitude)
+	}
+	if lf.Zenith < 0 || lf.Zenith > 180 {
+		r.add(SeverityError, -1, "zenith", "%g is outside [0, 180]", lf.Zenith)
+	}
+	for _, w := range lf.Warnings {
+		r.add(SeverityWarning, w.Profile, w.Kind.String(), "%s (offset %d, recovered by lenient parsing)", w.Message, w.Offset)
+	}
+
+	if lf.HeaderOnly {
+		r.add(SeverityInfo, -1, "data", "file was loaded header-only, profile data not checked")
+	}
+
+	for i := range lf.Profiles {
+		lf.Profiles[i].validate(&r, i, !lf.HeaderOnly)
+	}
+	return r
+}
+
+// validate — проверки одного профиля; withData — проверять ли Data
+func (lp *LicelProfile) validate(r *ValidationReport, i int, withData bool) {
+	if withData && lp.NDataPoints != len(lp.Data) {
+		r.add(SeverityError, i, "data points", "NDataPoints is %d, but Data has %d values", lp.NDataPoints, len(lp.Data))
+	}
+	if withData && lp.Raw != nil && len(lp.Raw) != len(lp.Data) {
+		r.add(SeverityWarning, i, "raw", "Raw has %d values, Data has %d; Raw will be ignored when writing", len(lp.Raw), len(lp.Data))
+	}
+	if lp.BinWidth <= 0 {
+		r.add(SeverityError, i, "bin width", "must be positive, got %g", lp.BinWidth)
+	}
+	if lp.NShots <= 0 {
+		r.add(SeverityError, i, "n shots", "must be positive for scaling, got %d", lp.NShots)
+	}
+	if !knownDeviceIDs[lp.DeviceID] {
+		r.add(SeverityWarning, i, "device", "unknown device ID %q", lp.DeviceID)
+	}
+	if lp.IsAnalog() && (lp.AdcBits <= 0 || lp.AdcBits > 24) {
+		r.add(SeverityWarning, i, "adc bits", "%d is outside [1, 24]", lp.AdcBits)
+	}
+	if (lp.IsPhoton() && !lp.Photon) || (lp.IsAnalog() && lp.Photon) {
+		r.add(SeverityWarning, i, "photon", "photon flag %t contradicts device ID %q", lp.Photon, lp.DeviceID)
+	}
+	if lp.Wavelength <= 0 {
+		r.add(SeverityWarning, i, "wavelength", "must be positive, got %g", lp.Wavelength)
+	}
+	if lp.Truncated {
+		r.add(SeverityWarning, i, "data", "profile is truncated to %d points", lp.NDataPoints)
+	}
+}
+
+// Validate — проверяет каждый файл пака и согласованность файлов между собой:
+// у одного канала (длина волны, поляризация, устройство) ширина бина должна совпадать.
+// Проблемы файлов помечены полем File.
+func (lp *LicelPack) Validate() ValidationReport {
+	var r ValidationReport
+	names := lp.Names()
+	for _, name := range names {
+		lf := lp.Data[name]
+		fr := lf.Validate()
+		for _, vi := range fr.Issues {
+			vi.File = name
+			r.Issues = append(r.Issues, vi)
+		}
+	}
+
+	// Ширина бина по каналам: значение → файлы
+	widths := make(map[Channel]map[float64][]string)
+	var order []Channel
+	for _, name := range names {
+		for _, pr := range lp.Data[name].Profiles {
+			ch := Channel{Wavelength: pr.Wavelength, Polarization: pr.Polarization, DeviceID: pr.DeviceID}
+			if widths[ch] == nil {
+				widths[ch] = make(map[float64][]string)
+				order = append(order, ch)
+			}
+			files := widths[ch][pr.BinWidth]
+			if len(files) == 0 || files[len(files)-1] != name {
+				widths[ch][pr.BinWidth] = append(files, name)
+			}
+		}
+	}
+	for _, ch := range order {
+		if len(widths[ch]) < 2 {
+			continue
+		}
+		bws := make([]float64, 0, len(widths[ch]))
+		for bw := range widths[ch] {
+			bws = append(bws, bw)
+		}
+		sort.Float64s(bws)
+		parts := make([]string, len(bws))
+		for k, bw := range bws {
+			parts[k] = fmt.Sprintf("%g m in %s", bw, strings.Join(widths[ch][bw], ", "))
+		}
+		r.add(SeverityError, -1, "bin width", "channel %g.%s %s has different bin widths: %s",
+			ch.Wavelength, ch.Polarization, ch.DeviceID, strings.Join(parts, "; "))
+	}
+	return r
+}
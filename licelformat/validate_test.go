@@ -0,0 +1,164 @@
+package licelformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// validFile — корректный файл с одним фотонным профилем
+func validFile() LicelFile {
+	start := time.Date(2020, 2, 10, 19, 22, 35, 0, time.UTC)
+	return LicelFile{
+		MeasurementSite:      "Test",
+		MeasurementStartTime: start,
+		MeasurementStopTime:  start.Add(100 * time.Second),
+		Longitude:            131.9,
+		Latitude:             43.1,
+		Zenith:               50,
+		NDatasets:            1,
+		Profiles: LicelProfilesList{{
+			Active: true, Photon: true, NDataPoints: 3, BinWidth: 7.5,
+			Wavelength: 532, Polarization: "p", NShots: 600, DeviceID: DeviceIDPhoton,
+			Data: []float64{1, 2, 3},
+		}},
+	}
+}
+
+// issueFields — поля проблем отчёта в виде "severity field"
+func issueFields(r ValidationReport) []string {
+	res := make([]string, 0, len(r.Issues))
+	for _, vi := range r.Issues {
+		res = append(res, vi.Severity.String()+" "+vi.Field)
+	}
+	return res
+}
+
+// --- Severity ---
+
+func TestSeverity_String(t *testing.T) {
+	assert.Equal(t, "info", SeverityInfo.String())
+	assert.Equal(t, "warning", SeverityWarning.String())
+	assert.Equal(t, "error", SeverityError.String())
+	assert.Equal(t, "Severity(7)", Severity(7).String())
+}
+
+// --- LicelFile.Validate ---
+
+func TestLicelFile_Validate_Testdata(t *testing.T) {
+	lf, err := LoadLicelFile(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	r := lf.Validate()
+	assert.Empty(t, r.Issues)
+	assert.False(t, r.HasErrors())
+}
+
+func TestLicelFile_Validate_Valid(t *testing.T) {
+	lf := validFile()
+	r := lf.Validate()
+	assert.Empty(t, r.Issues)
+}
+
+func TestLicelFile_Validate_FileInvariants(t *testing.T) {
+	lf := validFile()
+	lf.NDatasets = 2
+	lf.MeasurementStopTime = lf.MeasurementStartTime.Add(-time.Second)
+	lf.Latitude = 91
+	lf.Longitude = -181
+	lf.Zenith = 190
+
+	r := lf.Validate()
+	assert.Equal(t, []string{
+		"error dataset count",
+		"error stop time",
+		"error latitude",
+		"error longitude",
+		"error zenith",
+	}, issueFields(r))
+	assert.True(t, r.HasErrors())
+	for _, vi := range r.Issues {
+		assert.Equal(t, -1, vi.Profile)
+	}
+}
+
+func TestLicelFile_Validate_ProfileInvariants(t *testing.T) {
+	lf := validFile()
+	pr := &lf.Profiles[0]
+	pr.NDataPoints = 4
+	pr.BinWidth = 0
+	pr.NShots = 0
+	pr.DeviceID = "XX"
+	pr.Wavelength = 0
+	pr.Truncated = true
+
+	r := lf.Validate()
+	assert.Equal(t, []string{
+		"error data points",
+		"error bin width",
+		"error n shots",
+		"warning device",
+		"warning wavelength",
+		"warning data",
+	}, issueFields(r))
+	assert.Equal(t, 3, r.Count(SeverityError))
+	assert.Equal(t, 6, r.Count(SeverityInfo))
+	for _, vi := range r.Issues {
+		assert.Equal(t, 0, vi.Profile)
+	}
+}
+
+func TestLicelFile_Validate_PhotonFlag(t *testing.T) {
+	lf := validFile()
+	lf.Profiles[0].Photon = false
+	r := lf.Validate()
+	assert.Equal(t, []string{"warning photon"}, issueFields(r))
+	assert.False(t, r.HasErrors())
+}
+
+func TestLicelFile_Validate_HeaderOnlySkipsData(t *testing.T) {
+	lf, err := LoadLicelHeader(filepath.Join("..", "testdata", "b2021019.223500"))
+	require.NoError(t, err)
+	r := lf.Validate()
+	assert.Equal(t, []string{"info data"}, issueFields(r))
+	assert.Equal(t, 0, r.Count(SeverityWarning))
+}
+
+func TestLicelFile_Validate_LenientWarnings(t *testing.T) {
+	full := lenientTestBytes("02")
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(full[:len(full)-8]), WithLenient())
+	require.NoError(t, err)
+	r := lf.Validate()
+	assert.False(t, r.HasErrors())
+	assert.Equal(t, 2, r.Count(SeverityWarning))
+}
+
+// --- LicelPack.Validate ---
+
+func TestLicelPack_Validate_BinWidths(t *testing.T) {
+	a, b, c := validFile(), validFile(), validFile()
+	c.Profiles[0].BinWidth = 3.75
+	c.Latitude = 100
+	pack := &LicelPack{Data: map[string]LicelFile{"a": a, "b": b, "c": c}}
+
+	r := pack.Validate()
+	require.Len(t, r.Issues, 2)
+	assert.Equal(t, "c", r.Issues[0].File)
+	assert.Equal(t, "latitude", r.Issues[0].Field)
+	assert.Equal(t, "", r.Issues[1].File)
+	assert.Equal(t, SeverityError, r.Issues[1].Severity)
+	assert.Equal(t, "error: bin width: channel 532.p BC has different bin widths: 3.75 m in c; 7.5 m in a, b", r.Issues[1].String())
+}
+
+func TestValidationReport_JSON(t *testing.T) {
+	lf := validFile()
+	lf.Zenith = -1
+	r := lf.Validate()
+	b, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"issues":[{"severity":"error","profile":-1,"field":"zenith","message":"-1 is outside [0, 180]"}]}`, string(b))
+}
@@ -0,0 +1,67 @@
+package licelformat
+
+import "fmt"
+
+// WarningKind — тип проблемы, обнаруженной при нестрогом разборе (WithLenient)
+type WarningKind int
+
+const (
+	WarnShortData    WarningKind = iota // данных профиля меньше, чем NDataPoints
+	WarnMissingCRLF                     // после данных профиля нет \r\n
+	WarnDatasetCount                    // число заголовков или блоков данных не совпадает с NDatasets
+)
+
+// String — название типа предупреждения
+func (k WarningKind) String() string {
+	switch k {
+	case WarnShortData:
+		return "short data"
+	case WarnMissingCRLF:
+		return "missing CRLF"
+	case WarnDatasetCount:
+		return "dataset count mismatch"
+	}
+	return fmt.Sprintf("WarningKind(%d)", int(k))
+}
+
+// MarshalText — тип предупреждения в JSON выводится названием
+func (k WarningKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// UnmarshalText — тип предупреждения по названию из MarshalText
+func (k *WarningKind) UnmarshalText(text []byte) error {
+	for v := WarnShortData; v <= WarnDatasetCount; v++ {
+		if v.String() == string(text) {
+			*k = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown warning kind %q", text)
+}
+
+// ParseWarning — проблема в файле, которую нестрогий разбор обошёл
+type ParseWarning struct {
+	Kind    WarningKind `json:"kind"`    // тип проблемы
+	Profile int         `json:"profile"` // индекс профиля в файле; -1 — проблема всего файла
+	Offset  int64       `json:"offset"`  // смещение от начала файла, байт
+	Message string      `json:"message"` // подробности
+}
+
+// String — описание предупреждения в одну строку
+func (w ParseWarning) String() string {
+	if w.Profile < 0 {
+		return fmt.Sprintf("%s at offset %d: %s", w.Kind, w.Offset, w.Message)
+	}
+	return fmt.Sprintf("%s in profile %d at offset %d: %s", w.Kind, w.Profile, w.Offset, w.Message)
+}
+
+// warn — добавляет предупреждение к файлу
+func (lf *LicelFile) warn(kind WarningKind, profile int, offset int64, format string, args ...any) {
+	lf.Warnings = append(lf.Warnings, ParseWarning{
+		Kind:    kind,
+		Profile: profile,
+		Offset:  offset,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,186 @@
+package licelformat
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lenientTestBytes — файл с двумя фотонными профилями по 3 точки;
+// datasets — значение NDatasets в третьей строке
+func lenientTestBytes(datasets string) []byte {
+	return makeLicelBytes(
+		[]string{
+			" x2020041.120000",
+			" Site 10/02/2020 19:22:35 10/02/2020 19:24:15 0020 0131.9 0043.1 50",
+			" 0000600 0010 0000000 0000 " + datasets + " 0000000 0000",
+		},
+		[]string{
+			" 1 1 1 00003 1 0850 3.75 00532.p 0 0 00 000 00 000600 3.1746 BC0",
+			" 1 1 1 00003 1 0850 3.75 00355.o 0 0 00 000 00 000600 3.1746 BC1",
+		},
+		[][]int32{{1, 2, 3}, {4, 5, 6}},
+	)
+}
+
+// --- WarningKind ---
+
+func TestWarningKind_String(t *testing.T) {
+	assert.Equal(t, "short data", WarnShortData.String())
+	assert.Equal(t, "missing CRLF", WarnMissingCRLF.String())
+	assert.Equal(t, "dataset count mismatch", WarnDatasetCount.String())
+	assert.Equal(t, "WarningKind(42)", WarningKind(42).String())
+}
+
+func TestParseWarning_JSON_RoundTrip(t *testing.T) {
+	lf := LicelFile{Warnings: []ParseWarning{{Kind: WarnMissingCRLF, Profile: 1, Offset: 42, Message: "x"}}}
+	b, err := json.Marshal(lf)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"kind":"missing CRLF"`)
+
+	var got LicelFile
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, lf.Warnings, got.Warnings)
+
+	var k WarningKind
+	assert.Error(t, k.UnmarshalText([]byte("bogus")))
+}
+
+func TestParseWarning_String(t *testing.T) {
+	w := ParseWarning{Kind: WarnShortData, Profile: 1, Offset: 300, Message: "expected 3 data points, got 1"}
+	assert.Equal(t, "short data in profile 1 at offset 300: expected 3 data points, got 1", w.String())
+	w = ParseWarning{Kind: WarnDatasetCount, Profile: -1, Offset: 10, Message: "x"}
+	assert.Equal(t, "dataset count mismatch at offset 10: x", w.String())
+}
+
+// --- Lenient loading ---
+
+func TestLoadLicelFile_Lenient_CleanFile(t *testing.T) {
+	raw := lenientTestBytes("02")
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	assert.Empty(t, lf.Warnings)
+	require.Len(t, lf.Profiles, 2)
+
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	assert.Equal(t, string(raw), buf.String())
+}
+
+func TestLoadLicelFile_Lenient_ShortData(t *testing.T) {
+	full := lenientTestBytes("02")
+	// Обрезаем середину второго отсчёта второго профиля: остаётся 6 байт из 12
+	raw := full[:len(full)-2-6]
+
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.Error(t, err)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	assert.True(t, lf.FileLoaded)
+	require.Len(t, lf.Profiles, 2)
+	assert.Equal(t, 2, lf.NDatasets)
+	assert.False(t, lf.Profiles[0].Truncated)
+	assert.True(t, lf.Profiles[1].Truncated)
+	assert.Equal(t, 1, lf.Profiles[1].NDataPoints)
+	assert.Equal(t, []int32{4}, lf.Profiles[1].Raw)
+
+	require.Len(t, lf.Warnings, 1)
+	assert.Equal(t, WarnShortData, lf.Warnings[0].Kind)
+	assert.Equal(t, 1, lf.Warnings[0].Profile)
+	assert.Equal(t, lf.Profiles[1].DataOffset, lf.Warnings[0].Offset)
+}
+
+func TestLoadLicelFile_Lenient_MissingProfileData(t *testing.T) {
+	full := lenientTestBytes("02")
+	// Файл кончается сразу после первого профиля
+	raw := full[:len(full)-12-2]
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	require.Len(t, lf.Profiles, 1)
+	assert.Equal(t, 1, lf.NDatasets)
+	assert.Equal(t, []int32{1, 2, 3}, lf.Profiles[0].Raw)
+
+	require.Len(t, lf.Warnings, 2)
+	assert.Equal(t, WarnShortData, lf.Warnings[0].Kind)
+	assert.Equal(t, 1, lf.Warnings[0].Profile)
+	assert.Equal(t, WarnDatasetCount, lf.Warnings[1].Kind)
+	assert.Equal(t, -1, lf.Warnings[1].Profile)
+}
+
+func TestLoadLicelFile_Lenient_MissingCRLF(t *testing.T) {
+	full := lenientTestBytes("02")
+	// Удаляем CRLF после данных первого профиля
+	end := len(full) - 2 - 12 - 2
+	raw := append(append([]byte{}, full[:end]...), full[end+2:]...)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	require.Len(t, lf.Profiles, 2)
+	assert.Equal(t, []int32{1, 2, 3}, lf.Profiles[0].Raw)
+	assert.Equal(t, []int32{4, 5, 6}, lf.Profiles[1].Raw)
+	assert.Equal(t, int64(end), lf.Profiles[1].DataOffset)
+
+	require.Len(t, lf.Warnings, 1)
+	assert.Equal(t, WarnMissingCRLF, lf.Warnings[0].Kind)
+	assert.Equal(t, 0, lf.Warnings[0].Profile)
+}
+
+func TestLoadLicelFile_Lenient_TooManyDeclared(t *testing.T) {
+	raw := lenientTestBytes("03")
+
+	_, err := LoadLicelFileFromReader(bytes.NewReader(raw))
+	require.Error(t, err)
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	require.Len(t, lf.Profiles, 2)
+	assert.Equal(t, 2, lf.NDatasets)
+	assert.Equal(t, []int32{4, 5, 6}, lf.Profiles[1].Raw)
+	require.Len(t, lf.Warnings, 1)
+	assert.Equal(t, WarnDatasetCount, lf.Warnings[0].Kind)
+
+	// Третья строка перезаписывается с исправленным NDatasets
+	var buf bytes.Buffer
+	require.NoError(t, lf.WriteTo(&buf, ""))
+	back, err := LoadLicelFileFromReader(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 2, back.NDatasets)
+}
+
+func TestLoadLicelFile_Lenient_TooFewDeclared(t *testing.T) {
+	raw := lenientTestBytes("01")
+
+	lf, err := LoadLicelFileFromReader(bytes.NewReader(raw), WithLenient())
+	require.NoError(t, err)
+	require.Len(t, lf.Profiles, 2)
+	assert.Equal(t, 2, lf.NDatasets)
+	assert.Equal(t, 355.0, lf.Profiles[1].Wavelength)
+	assert.Equal(t, []int32{4, 5, 6}, lf.Profiles[1].Raw)
+	require.Len(t, lf.Warnings, 1)
+	assert.Equal(t, WarnDatasetCount, lf.Warnings[0].Kind)
+}
+
+func TestNewLicelPack_Lenient(t *testing.T) {
+	dir := t.TempDir()
+	full := lenientTestBytes("02")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.dat"), full, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.dat"), full[:len(full)-6], 0o644))
+
+	_, err := NewLicelPack(filepath.Join(dir, "*.dat"))
+	require.Error(t, err)
+
+	pack, err := NewLicelPack(filepath.Join(dir, "*.dat"), WithLenient())
+	require.NoError(t, err)
+	require.Len(t, pack.Data, 2)
+	assert.Empty(t, pack.Data[filepath.Join(dir, "a.dat")].Warnings)
+	b := pack.Data[filepath.Join(dir, "b.dat")]
+	require.Len(t, b.Warnings, 1)
+	assert.True(t, b.Profiles[1].Truncated)
+}